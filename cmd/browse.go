@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+// browseCmd represents the browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browse clipboard items in an interactive terminal UI",
+	Long: `Browse clipboard items in a full-screen terminal UI.
+
+The UI is drawn on stderr. Pressing enter prints the selected item's text to
+stdout, so the command composes with shell pipelines and substitutions.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		var items []history.HistoryItem
+		if where != nil {
+			items, err = historyRepo.GetItems(history.Filter{Where: where})
+		} else {
+			items, err = historyRepo.GetAllItems()
+		}
+		if err != nil {
+			cmd.PrintErrln("Error retrieving items:", err)
+			return
+		}
+
+		items, _, err = redactItems(items)
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		selected, err := tui.Run(items)
+		if err != nil {
+			cmd.PrintErrln("Error running browser:", err)
+			return
+		}
+
+		if selected != nil {
+			fmt.Print(selected.DecodedText())
+		}
+	},
+}
+
+func init() {
+	addWhereFlag(browseCmd)
+	addRedactFlags(browseCmd)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/cluster"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clustersTableFormat bool
+	clustersMethod      string
+	clustersThreshold   float64
+	clustersMinSize     int
+)
+
+// clustersCmd represents the clusters command
+var clustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "Group clipboard items with similar text",
+	Long: `Find clusters of near-duplicate clipboard items, such as commands or
+messages that were copied again after small edits.
+
+Decoded text is compared using MinHash (--method minhash, the default) or
+SimHash (--method simhash) signatures over character shingles, ignoring case
+and whitespace. Items whose similarity reaches --threshold end up in the same
+cluster, which is represented by its most recently copied item. The
+default threshold depends on the method: 0.7 for MinHash and 0.9 for
+SimHash, whose fingerprints agree on about half their bits even for
+unrelated text.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		method, err := cluster.ParseMethod(clustersMethod)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		if !cmd.Flags().Changed("threshold") {
+			clustersThreshold = method.DefaultThreshold()
+		} else if clustersThreshold <= 0 || clustersThreshold > 1 {
+			cmd.PrintErrln("Error: --threshold must be greater than 0 and at most 1")
+			return
+		}
+
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		filter := history.Filter{Where: where}
+		items, err := loadItems(cmd.Context(), filter, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			if where != nil {
+				return repo.GetItems(filter)
+			}
+			return repo.GetAllItems()
+		})
+		if err != nil {
+			cmd.PrintErrln("Error retrieving items:", err)
+			return
+		}
+
+		clusters := cluster.FindClusters(items, cluster.Options{
+			Method:    method,
+			Threshold: clustersThreshold,
+			MinSize:   clustersMinSize,
+		})
+
+		redactor, err := newRedactor()
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+		for i := range clusters {
+			clusters[i].Preview = redactPreview(redactor, clusters[i].Preview)
+		}
+
+		if clustersTableFormat {
+			printClusters(clusters)
+			return
+		}
+
+		if clusters == nil {
+			clusters = []cluster.Cluster{}
+		}
+		jsonData, err := json.MarshalIndent(clusters, "", "  ")
+		if err != nil {
+			cmd.PrintErrln("Error formatting JSON:", err)
+			return
+		}
+		fmt.Println(string(jsonData))
+	},
+}
+
+// printClusters prints clusters in a table
+func printClusters(clusters []cluster.Cluster) {
+	if len(clusters) == 0 {
+		fmt.Println("No clusters found.")
+		return
+	}
+
+	t := table.New(
+		table.Column{Header: "Cluster", Align: table.AlignRight},
+		table.Column{Header: "Size", Align: table.AlignRight},
+		table.Column{Header: "Copies", Align: table.AlignRight},
+		table.Column{Header: "Rep", Align: table.AlignRight},
+		table.Column{Header: "Sim", Align: table.AlignRight},
+		table.Column{Header: "First Copied"},
+		table.Column{Header: "Last Copied"},
+		table.Column{Header: "Span"},
+		table.Column{Header: "Preview", Max: 60, Flex: true},
+	)
+	for _, c := range clusters {
+		t.Append(fmt.Sprint(c.ID), fmt.Sprint(c.Size), fmt.Sprint(c.TotalCopies), fmt.Sprint(c.Representative),
+			fmt.Sprintf("%.2f", c.Similarity), history.FormatTime(c.FirstCopiedAt),
+			history.FormatTime(c.LastCopiedAt), c.Span, c.Preview)
+	}
+	t.Render(os.Stdout)
+}
+
+func init() {
+	clustersCmd.Flags().BoolVarP(&clustersTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	clustersCmd.Flags().StringVarP(&clustersMethod, "method", "m", string(cluster.MethodMinHash), "Similarity signature: minhash or simhash")
+	clustersCmd.Flags().Float64Var(&clustersThreshold, "threshold", 0,
+		"Minimum similarity between 0 and 1 for items to be clustered (default: 0.7 for minhash, 0.9 for simhash)")
+	clustersCmd.Flags().IntVar(&clustersMinSize, "min-size", 2, "Only report clusters with at least this many items")
+	addWhereFlag(clustersCmd)
+	addRedactFlags(clustersCmd)
+	addSourceFlags(clustersCmd)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/dedupe"
+	"github.com/gkwa/sunlitsparrow/internal/export"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/redact"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dedupeTableFormat bool
+	dedupeMode        string
+	dedupeApply       bool
+	dedupeForce       bool
+	dedupeBackupDir   string
+	dedupeExportFile  string
+	dedupeEncrypt     bool
+)
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and collapse duplicate clipboard items",
+	Long: `Group clipboard items that are duplicates of each other, either by exact
+content (--mode exact) or by text compared ignoring case and whitespace
+(--mode normalized, the default), and report each group with its combined
+copy count.
+
+Duplicates can be collapsed into one item per group, keeping pinned items
+and otherwise the most recently copied one, either in an export written with
+--export or in the database with --apply (after an automatic backup). Pinned
+items are never merged away: when a group holds several, all of them are
+kept and listed as keptPinned.
+
+--where limits duplicate detection to matching items; the --export file
+then holds only those items, and --apply only merges among them.
+
+Reports and exports only read the database, or an export given with
+--from. Only --apply opens the Maccy database for writing, refusing while
+another process holds a write lock unless --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := dedupe.ParseMode(dedupeMode)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		if dedupeEncrypt && dedupeExportFile == "" {
+			cmd.PrintErrln("Error: --encrypt requires --export")
+			return
+		}
+		if keyFlagsSet() && dedupeExportFile == "" && fromFile == "" {
+			cmd.PrintErrln("Error: --passphrase-file and --key-file require --export or --from")
+			return
+		}
+		if dedupeApply && fromFile != "" {
+			cmd.PrintErrln("Error: --apply changes the Maccy database and cannot be combined with --from")
+			return
+		}
+
+		filter := history.Filter{Where: where}
+		items, err := loadItems(cmd.Context(), filter, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			if where != nil {
+				return repo.GetItems(filter)
+			}
+			return repo.GetAllItems()
+		})
+		if err != nil {
+			cmd.PrintErrln("Error retrieving items:", err)
+			return
+		}
+
+		// Groups are found on the raw items so that redaction cannot make
+		// different items look identical; only what is shown is masked
+		groups := dedupe.FindGroups(items, mode)
+		redactor, err := newRedactor()
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+		shown := make([]dedupe.Group, len(groups))
+		for i, group := range groups {
+			group.Preview = redactPreview(redactor, group.Preview)
+			shown[i] = group
+		}
+
+		if dedupeTableFormat {
+			printGroups(shown)
+		} else {
+			jsonData, err := json.MarshalIndent(shown, "", "  ")
+			if err != nil {
+				cmd.PrintErrln("Error formatting JSON:", err)
+				return
+			}
+			fmt.Println(string(jsonData))
+		}
+
+		if dedupeExportFile != "" {
+			collapsed := dedupe.Collapse(items, groups)
+			exporter := export.NewJSONExporter(dedupeExportFile)
+			if redactor != nil {
+				var records []redact.Record
+				collapsed, records = redactor.RedactItems(collapsed)
+				exporter.SetRedactions(records)
+			}
+			if dedupeEncrypt || keyFlagsSet() {
+				key, err := readKey(true)
+				if err != nil {
+					cmd.PrintErrln("Error reading encryption key:", err)
+					return
+				}
+				exporter.SetEncryption(key)
+			}
+			if err := exporter.Export(collapsed); err != nil {
+				cmd.PrintErrln("Error exporting items:", err)
+				return
+			}
+			cmd.PrintErrf("Exported %d deduplicated items to %s\n", len(collapsed), dedupeExportFile)
+		}
+
+		merges := dedupe.Merges(groups)
+		if !dedupeApply || len(merges) == 0 {
+			return
+		}
+
+		// The write lock is only taken once there is something to write
+		session, err := openForWrite(cmd.Context(), dedupeForce)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		defer session.Close()
+
+		backupPath, err := session.backup(dedupeBackupDir)
+		if err != nil {
+			cmd.PrintErrln("Error backing up database, nothing was changed:", err)
+			return
+		}
+		cmd.PrintErrf("Backed up database to %s\n", backupPath)
+
+		if err := session.repo.MergeItems(merges); err != nil {
+			cmd.PrintErrln("Error collapsing duplicates:", err)
+			return
+		}
+
+		removed, keptPinned := 0, 0
+		for _, group := range groups {
+			removed += len(group.Duplicates())
+			keptPinned += len(group.KeptPinned)
+		}
+		cmd.PrintErrf("Collapsed %d groups, removing %d duplicate items.\n", len(merges), removed)
+		if keptPinned > 0 {
+			cmd.PrintErrf("Kept %d pinned duplicates; unpin them first to merge them.\n", keptPinned)
+		}
+	},
+}
+
+// printGroups prints duplicate groups in a table
+func printGroups(groups []dedupe.Group) {
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found.")
+		return
+	}
+
+	t := table.New(
+		table.Column{Header: "Group"},
+		table.Column{Header: "Items", Align: table.AlignRight},
+		table.Column{Header: "Copies", Align: table.AlignRight},
+		table.Column{Header: "Keep", Align: table.AlignRight},
+		table.Column{Header: "Item IDs", Max: 25, Flex: true},
+		table.Column{Header: "Kept Pinned", Max: 15, Flex: true},
+		table.Column{Header: "Preview", Max: 60, Flex: true},
+	)
+	for _, group := range groups {
+		t.Append(group.Key, fmt.Sprint(len(group.ItemIDs)), fmt.Sprint(group.TotalCopies), fmt.Sprint(group.Keep),
+			joinIDs(group.ItemIDs), joinIDs(group.KeptPinned), group.Preview)
+	}
+	t.Render(os.Stdout)
+}
+
+// joinIDs formats item IDs as a comma-separated list
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ",")
+}
+
+func init() {
+	dedupeCmd.Flags().BoolVarP(&dedupeTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	dedupeCmd.Flags().StringVarP(&dedupeMode, "mode", "m", string(dedupe.ModeNormalized), "Duplicate detection: exact or normalized")
+	dedupeCmd.Flags().BoolVar(&dedupeApply, "apply", false, "Collapse duplicates in the database")
+	dedupeCmd.Flags().BoolVar(&dedupeForce, "force", false, "Write even if the database appears to be locked")
+	dedupeCmd.Flags().StringVar(&dedupeBackupDir, "backup-dir", "", "Directory for the automatic backup (default: next to the database)")
+	dedupeCmd.Flags().StringVarP(&dedupeExportFile, "export", "e", "", "Write all items with duplicates collapsed to this JSON file")
+	dedupeCmd.Flags().BoolVar(&dedupeEncrypt, "encrypt", false, "Encrypt the --export file with a passphrase or key file")
+	addSourceFlags(dedupeCmd)
+	addRedactFlags(dedupeCmd)
+	addWhereFlag(dedupeCmd)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dumpOutputFile string
+	dumpTables     []string
+	dumpSince      string
+	dumpUntil      string
+	dumpApps       []string
+	dumpType       string
+	dumpSearch     string
+	dumpPinned     bool
+	dumpLimit      int
+)
+
+// dumpCmd represents the dump command
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Dump the database as a re-loadable SQL script",
+	Long: `Write a SQL script that recreates the Maccy database, including tables,
+rows, indexes, triggers and views, for example to reproduce a user's
+database in a bug report:
+
+  sunlitsparrow dump -o maccy.sql
+  sqlite3 copy.sqlite < maccy.sql
+
+--table limits the dump to selected tables. The history filters (--since,
+--until, --app, --type, --search, --pinned, --where and --limit) limit the rows of the
+item and content tables to the matching items; other tables are dumped in
+full.
+
+The dump holds the raw clipboard contents, including any passwords or
+tokens that were copied. Review it before sharing, or pass --redact to mask
+sensitive data in the text and UTF-8 values of the item and content rows.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		opts := schema.DumpOptions{
+			Tables:      dumpTables,
+			ItemColumns: history.ItemTables(),
+		}
+		redactor, err := newRedactor()
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+		if redactor != nil {
+			opts.Redact = redactor.RedactText
+		}
+
+		filterFlags := []string{"since", "until", "app", "type", "search", "pinned", "limit", "where"}
+		for _, name := range filterFlags {
+			if !cmd.Flags().Changed(name) {
+				continue
+			}
+			filter, err := dumpFilter()
+			if err != nil {
+				cmd.PrintErrln("Error:", err)
+				return
+			}
+			items, err := history.NewRepository(dbConn).WithContext(cmd.Context()).GetItems(filter)
+			if err != nil {
+				cmd.PrintErrln("Error retrieving items:", err)
+				return
+			}
+			opts.ItemIDs = map[int]bool{}
+			for _, item := range items {
+				opts.ItemIDs[item.ID] = true
+			}
+			break
+		}
+
+		var out io.Writer = os.Stdout
+		if dumpOutputFile != "" {
+			file, err := os.Create(dumpOutputFile)
+			if err != nil {
+				cmd.PrintErrln("Error creating output file:", err)
+				return
+			}
+			defer file.Close()
+			out = file
+		}
+
+		buffered := bufio.NewWriter(out)
+		stats, err := schema.NewExplorer(dbConn).Dump(buffered, opts)
+		if err == nil {
+			err = buffered.Flush()
+		}
+		if err != nil {
+			cmd.PrintErrln("Error dumping database:", err)
+			return
+		}
+
+		if dumpOutputFile != "" {
+			cmd.PrintErrf("Dumped %d tables with %d rows to %s\n", stats.Tables, stats.Rows, dumpOutputFile)
+		}
+	},
+}
+
+// dumpFilter builds the history filter selecting the items whose rows are dumped
+func dumpFilter() (history.Filter, error) {
+	filter := history.Filter{
+		Applications: dumpApps,
+		ContentType:  dumpType,
+		Text:         dumpSearch,
+		PinnedOnly:   dumpPinned,
+		Limit:        dumpLimit,
+	}
+
+	var err error
+	if filter.Where, err = parseWhere(); err != nil {
+		return filter, err
+	}
+	if filter.Since, err = history.ParseTime(dumpSince); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = history.ParseTime(dumpUntil); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func init() {
+	dumpCmd.Flags().StringVarP(&dumpOutputFile, "output", "o", "", "Write the script to this file instead of stdout")
+	dumpCmd.Flags().StringSliceVarP(&dumpTables, "table", "T", nil, "Only dump these tables (repeatable)")
+	dumpCmd.Flags().StringVar(&dumpSince, "since", "", "Only items last copied after this time, date or duration ago (e.g. 24h)")
+	dumpCmd.Flags().StringVar(&dumpUntil, "until", "", "Only items last copied at or before this time, date or duration ago")
+	dumpCmd.Flags().StringSliceVarP(&dumpApps, "app", "a", nil, "Only items copied from these applications")
+	dumpCmd.Flags().StringVar(&dumpType, "type", "", "Only items with a content of this pasteboard type")
+	dumpCmd.Flags().StringVarP(&dumpSearch, "search", "s", "", "Only items whose title or text contains this text")
+	dumpCmd.Flags().BoolVarP(&dumpPinned, "pinned", "p", false, "Only pinned items")
+	addWhereFlag(dumpCmd)
+	dumpCmd.Flags().IntVarP(&dumpLimit, "limit", "l", 0, "Only the most recently copied items (0 for no limit)")
+	addRedactFlags(dumpCmd)
+}
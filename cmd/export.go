@@ -1,19 +1,27 @@
 package cmd
 
 import (
-	"github.com/gkwa/sunlitsparrow/internal/db"
 	"github.com/gkwa/sunlitsparrow/internal/export"
 	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/redact"
 	"github.com/spf13/cobra"
 )
 
+var exportEncrypt bool
+
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export [file]",
 	Short: "Export clipboard items to JSON",
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		dbConn, err := db.OpenMaccyDB()
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		dbConn, err := openMaccyDB()
 		if err != nil {
 			cmd.PrintErrln("Error opening database:", err)
 			return
@@ -25,14 +33,36 @@ var exportCmd = &cobra.Command{
 			outputFile = args[0]
 		}
 
-		historyRepo := history.NewRepository(dbConn)
-		items, err := historyRepo.GetAllItems()
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		var items []history.HistoryItem
+		if where != nil {
+			items, err = historyRepo.GetItems(history.Filter{Where: where})
+		} else {
+			items, err = historyRepo.GetAllItems()
+		}
 		if err != nil {
 			cmd.PrintErrln("Error retrieving items:", err)
 			return
 		}
 
 		exporter := export.NewJSONExporter(outputFile)
+		if redactionEnabled() {
+			var records []redact.Record
+			items, records, err = redactItems(items)
+			if err != nil {
+				cmd.PrintErrln("Error configuring redaction:", err)
+				return
+			}
+			exporter.SetRedactions(records)
+		}
+		if exportEncrypt || keyFlagsSet() {
+			key, err := readKey(true)
+			if err != nil {
+				cmd.PrintErrln("Error reading encryption key:", err)
+				return
+			}
+			exporter.SetEncryption(key)
+		}
 		if err := exporter.Export(items); err != nil {
 			cmd.PrintErrln("Error exporting items:", err)
 			return
@@ -41,3 +71,10 @@ var exportCmd = &cobra.Command{
 		cmd.Printf("Exported %d items to %s\n", len(items), outputFile)
 	},
 }
+
+func init() {
+	exportCmd.Flags().BoolVarP(&exportEncrypt, "encrypt", "e", false, "Encrypt the export with a passphrase or key file")
+	addKeyFlags(exportCmd)
+	addWhereFlag(exportCmd)
+	addRedactFlags(exportCmd)
+}
@@ -1,16 +1,14 @@
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
-
-	"github.com/gkwa/sunlitsparrow/internal/db"
 	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
 	"github.com/spf13/cobra"
 )
 
 var (
 	itemsTableFormat bool
+	itemsFormat      string
 	itemsLimit       int
 )
 
@@ -19,42 +17,58 @@ var itemsCmd = &cobra.Command{
 	Use:   "items",
 	Short: "List clipboard items",
 	Run: func(cmd *cobra.Command, args []string) {
-		dbConn, err := db.OpenMaccyDB()
+		format, err := resolveFormat(itemsFormat, itemsTableFormat)
 		if err != nil {
-			cmd.PrintErrln("Error opening database:", err)
+			cmd.PrintErrln("Error:", err)
 			return
 		}
-		defer dbConn.Close()
 
-		historyRepo := history.NewRepository(dbConn)
-		items, err := historyRepo.GetRecentItems(itemsLimit)
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		filter := history.Filter{Limit: itemsLimit, Where: where}
+		if err := applyPageFlags(&filter); err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		items, err := loadItems(cmd.Context(), filter, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			if where != nil || paging() {
+				return repo.GetItems(filter)
+			}
+			return repo.GetRecentItems(itemsLimit)
+		})
 		if err != nil {
 			cmd.PrintErrln("Error retrieving items:", err)
 			return
 		}
 
-		if len(items) == 0 {
+		items, _, err = redactItems(items)
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		if len(items) == 0 && !isMachineFormat(format) {
 			cmd.Println("No items found.")
 			return
 		}
 
-		if itemsTableFormat {
-			// Print in table format
-			printer := history.NewPrinter(items)
-			printer.PrintItems()
-		} else {
-			// Print in JSON format (default)
-			jsonData, err := json.MarshalIndent(items, "", "  ")
-			if err != nil {
-				cmd.PrintErrln("Error formatting JSON:", err)
-				return
-			}
-			fmt.Println(string(jsonData))
+		if err := printPage(items, format, filter); err != nil {
+			cmd.PrintErrln("Error printing items:", err)
 		}
 	},
 }
 
 func init() {
 	itemsCmd.Flags().BoolVarP(&itemsTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	itemsCmd.Flags().StringVarP(&itemsFormat, "format", "f", string(output.FormatJSON), "Output format: "+output.FormatNames())
 	itemsCmd.Flags().IntVarP(&itemsLimit, "limit", "l", 10, "Limit the number of items to display")
+	addWhereFlag(itemsCmd)
+	addPageFlags(itemsCmd)
+	addFieldFlags(itemsCmd)
+	addRedactFlags(itemsCmd)
+	addSourceFlags(itemsCmd)
 }
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/crypt"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	passphraseFile string
+	keyFile        string
+)
+
+// addKeyFlags registers the flags selecting the secret for encrypted exports
+func addKeyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "Read the export passphrase from a file (default: $SUNLITSPARROW_PASSPHRASE or prompt)")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "Use the contents of a key file instead of a passphrase")
+}
+
+// keyFlagsSet reports whether a passphrase file or key file was given
+func keyFlagsSet() bool {
+	return passphraseFile != "" || keyFile != ""
+}
+
+// readKey returns the key selected by the key flags, falling back to the
+// SUNLITSPARROW_PASSPHRASE environment variable and then to a terminal
+// prompt. With confirm set the prompted passphrase must be entered twice.
+func readKey(confirm bool) (crypt.Key, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return crypt.Key{}, fmt.Errorf("error reading key file: %w", err)
+		}
+		if len(data) < 16 {
+			return crypt.Key{}, errors.New("key file must contain at least 16 bytes")
+		}
+		return crypt.Key{KeyFile: data}, nil
+	}
+
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return crypt.Key{}, fmt.Errorf("error reading passphrase file: %w", err)
+		}
+		return passphraseKey(bytes.TrimRight(data, "\r\n"))
+	}
+
+	if env := os.Getenv("SUNLITSPARROW_PASSPHRASE"); env != "" {
+		return passphraseKey([]byte(env))
+	}
+
+	passphrase, err := promptPassphrase("Passphrase: ")
+	if err != nil {
+		return crypt.Key{}, err
+	}
+	if confirm {
+		again, err := promptPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return crypt.Key{}, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return crypt.Key{}, errors.New("passphrases do not match")
+		}
+	}
+	return passphraseKey(passphrase)
+}
+
+func passphraseKey(passphrase []byte) (crypt.Key, error) {
+	if len(passphrase) == 0 {
+		return crypt.Key{}, errors.New("empty passphrase")
+	}
+	return crypt.Key{Passphrase: passphrase}, nil
+}
+
+// promptPassphrase reads a passphrase from the controlling terminal without echo
+func promptPassphrase(prompt string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.New("no terminal available to prompt for a passphrase; use --passphrase-file, --key-file or $SUNLITSPARROW_PASSPHRASE")
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("error reading passphrase: %w", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fieldsSpec   string
+	templateText string
+	noContents   bool
+	binarySpec   string
+
+	// selectedFields, itemTemplate and binaryMode are parsed from --fields,
+	// --template and --binary by resolveFormat
+	selectedFields []output.Field
+	itemTemplate   *template.Template
+	binaryMode     output.BinaryMode
+)
+
+// addFieldFlags lets a listing command select fields, skip contents or
+// render items with a template
+func addFieldFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&fieldsSpec, "fields", "", "Comma-separated fields for JSON, YAML and table output: "+output.FieldNames())
+	cmd.Flags().BoolVar(&noContents, "no-contents", false, "Do not load item contents")
+	cmd.Flags().StringVar(&templateText, "template", "",
+		"Render each item with a Go template, e.g. '{{.ID}} {{.Title | truncate 30}} {{ago .LastCopiedAt}}'")
+	addBinaryFlag(cmd)
+}
+
+// addBinaryFlag lets a command choose how binary contents are written in
+// JSON and YAML output
+func addBinaryFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&binarySpec, "binary", "",
+		"Binary contents in json and yaml output: base64 or summary (default: base64 for json, summary otherwise)")
+}
+
+// resolveFormat returns the output format selected by --format, honoring the
+// --table shorthand, and parses --fields, --template and --binary
+func resolveFormat(name string, table bool) (output.Format, error) {
+	format := output.FormatTable
+	if !table {
+		var err error
+		if format, err = output.ParseFormat(name); err != nil {
+			return "", err
+		}
+	}
+
+	if fieldsSpec != "" {
+		if !format.IsStructured() && format != output.FormatTable {
+			return "", fmt.Errorf("--fields only applies to json, yaml and table output")
+		}
+		fields, err := output.ParseFields(fieldsSpec)
+		if err != nil {
+			return "", err
+		}
+		selectedFields = fields
+	}
+	if templateText != "" {
+		tmpl, err := output.ParseTemplate(templateText)
+		if err != nil {
+			return "", err
+		}
+		itemTemplate = tmpl
+	}
+
+	var err error
+	if binaryMode, err = output.ParseBinaryMode(binarySpec, format); err != nil {
+		return "", err
+	}
+	return format, nil
+}
+
+// parseStructuredFormat parses a --format limited to json and yaml
+func parseStructuredFormat(name string) (output.Format, error) {
+	format, err := output.ParseFormat(name)
+	if err != nil {
+		return "", err
+	}
+	if !format.IsStructured() {
+		return "", fmt.Errorf("unsupported format %q (expected json or yaml)", name)
+	}
+	return format, nil
+}
+
+// isMachineFormat reports whether the format is consumed by another program,
+// in which case empty results must still produce well-formed output
+func isMachineFormat(format output.Format) bool {
+	switch format {
+	case output.FormatFZF, output.FormatAlfred, output.FormatYAML:
+		return true
+	}
+	return format == output.FormatJSON && paged()
+}
+
+// printItems renders history items on stdout in the given format, or with
+// the --template when given
+func printItems(items []history.HistoryItem, format output.Format) error {
+	if itemTemplate != nil {
+		return output.WriteTemplate(os.Stdout, items, itemTemplate)
+	}
+
+	switch format {
+	case output.FormatTable:
+		if selectedFields != nil {
+			return output.WriteFieldsTable(os.Stdout, items, selectedFields)
+		}
+		return output.WriteItemsTable(os.Stdout, items)
+	case output.FormatFZF:
+		return output.WriteFZF(os.Stdout, items)
+	case output.FormatAlfred:
+		return output.WriteAlfred(os.Stdout, items)
+	default:
+		return output.EncodeItems(os.Stdout, format, items, selectedFields, binaryMode)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sortSpec    string
+	cursorToken string
+	paginate    bool
+)
+
+// itemPage is the JSON or YAML output of a paginated listing
+type itemPage struct {
+	Items      interface{} `json:"items" yaml:"items"`
+	NextCursor string      `json:"nextCursor,omitempty" yaml:"nextCursor,omitempty"`
+}
+
+// addPageFlags lets a listing command choose the sort order and page
+// through results with cursors
+func addPageFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sortSpec, "sort", "",
+		"Sort by lastCopiedAt, firstCopiedAt, numberOfCopies, title or size, optionally followed by :asc or :desc")
+	cmd.Flags().StringVar(&cursorToken, "cursor", "", "Continue after the page that returned this cursor")
+	cmd.Flags().BoolVar(&paginate, "paginate", false, "Wrap JSON or YAML output in an object with a cursor for the next page")
+}
+
+// paging reports whether the sort order or cursor were chosen, requiring
+// the repository's filtered query
+func paging() bool {
+	return sortSpec != "" || cursorToken != ""
+}
+
+// applyPageFlags sets the filter's sort order and cursor from the flags
+func applyPageFlags(filter *history.Filter) error {
+	var err error
+	if filter.Sort, err = history.ParseSort(sortSpec); err != nil {
+		return err
+	}
+	if cursorToken != "" {
+		if filter.After, err = history.ParseCursor(cursorToken, filter.Sort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPage renders a page of items. When paginating, JSON and YAML
+// output carries the next page's cursor and table or template output
+// reports it on stderr.
+func printPage(items []history.HistoryItem, format output.Format, filter history.Filter) error {
+	next := ""
+	if cursor := filter.NextCursor(items); cursor != nil {
+		next = cursor.String()
+	}
+
+	if format.IsStructured() && paged() && itemTemplate == nil {
+		return output.Encode(os.Stdout, format, itemPage{
+			Items:      output.ItemValues(items, selectedFields, binaryMode),
+			NextCursor: next,
+		})
+	}
+
+	if err := printItems(items, format); err != nil {
+		return err
+	}
+	if next != "" && (paging() || paginate) && (format == output.FormatTable || itemTemplate != nil) {
+		fmt.Fprintf(os.Stderr, "Next page: --cursor %s\n", next)
+	}
+	return nil
+}
+
+// paged reports whether structured output is wrapped with the next cursor
+func paged() bool {
+	return paginate || cursorToken != ""
+}
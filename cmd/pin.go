@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var pinForce bool
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin <id> [key]",
+	Short: "Pin a clipboard item to a key",
+	Long: `Pin a clipboard item to a key. Without a key the first free key is used.
+
+Keys are single letters accepted by Maccy (a, q, v, w and z are reserved).
+The command refuses to write while another process holds a write lock on the
+database unless --force is given.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			cmd.PrintErrln("Invalid item ID:", args[0])
+			return
+		}
+
+		key := ""
+		if len(args) > 1 {
+			key = strings.ToLower(args[1])
+			if !slices.Contains(history.PinKeys, key) {
+				cmd.PrintErrf("Invalid pin key %q (expected one of %s)\n", args[1], strings.Join(history.PinKeys, ""))
+				return
+			}
+		}
+
+		session, err := openForWrite(cmd.Context(), pinForce)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		defer session.Close()
+
+		if key == "" {
+			if key, err = freePinKey(session.repo); err != nil {
+				cmd.PrintErrln("Error:", err)
+				return
+			}
+		}
+
+		if err := session.repo.SetPin(id, key); err != nil {
+			cmd.PrintErrln("Error pinning item:", err)
+			return
+		}
+		cmd.Printf("Pinned item %d to %s\n", id, key)
+	},
+}
+
+// unpinCmd represents the unpin command
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <id>",
+	Short: "Remove the pin from a clipboard item",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			cmd.PrintErrln("Invalid item ID:", args[0])
+			return
+		}
+
+		session, err := openForWrite(cmd.Context(), pinForce)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		defer session.Close()
+
+		if err := session.repo.SetPin(id, ""); err != nil {
+			cmd.PrintErrln("Error unpinning item:", err)
+			return
+		}
+		cmd.Printf("Unpinned item %d\n", id)
+	},
+}
+
+// freePinKey returns the first pin key not assigned to any item
+func freePinKey(repo *history.Repository) (string, error) {
+	used, err := repo.UsedPins()
+	if err != nil {
+		return "", fmt.Errorf("error reading pins: %w", err)
+	}
+	for _, key := range history.PinKeys {
+		if _, taken := used[key]; !taken {
+			return key, nil
+		}
+	}
+	return "", errors.New("all pin keys are in use")
+}
+
+func init() {
+	pinCmd.Flags().BoolVar(&pinForce, "force", false, "Write even if the database appears to be locked")
+	unpinCmd.Flags().BoolVar(&pinForce, "force", false, "Write even if the database appears to be locked")
+}
@@ -1,56 +1,74 @@
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
-
-	"github.com/gkwa/sunlitsparrow/internal/db"
 	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var tableFormat bool
+var (
+	tableFormat bool
+	pinsFormat  string
+	pinsLimit   int
+)
 
 // pinsCmd represents the pins command
 var pinsCmd = &cobra.Command{
 	Use:   "pins",
 	Short: "List pinned clipboard items",
 	Run: func(cmd *cobra.Command, args []string) {
-		dbConn, err := db.OpenMaccyDB()
+		format, err := resolveFormat(pinsFormat, tableFormat)
 		if err != nil {
-			cmd.PrintErrln("Error opening database:", err)
+			cmd.PrintErrln("Error:", err)
 			return
 		}
-		defer dbConn.Close()
 
-		historyRepo := history.NewRepository(dbConn)
-		pinnedItems, err := historyRepo.GetPinnedItems()
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		filter := history.Filter{PinnedOnly: true, Where: where, Limit: pinsLimit}
+		if err := applyPageFlags(&filter); err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		pinnedItems, err := loadItems(cmd.Context(), filter, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			if where != nil || paging() || pinsLimit > 0 {
+				return repo.GetItems(filter)
+			}
+			return repo.GetPinnedItems()
+		})
 		if err != nil {
 			cmd.PrintErrln("Error retrieving pinned items:", err)
 			return
 		}
 
-		if len(pinnedItems) == 0 {
+		pinnedItems, _, err = redactItems(pinnedItems)
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		if len(pinnedItems) == 0 && !isMachineFormat(format) {
 			cmd.Println("No pinned items found.")
 			return
 		}
 
-		if tableFormat {
-			// Print in table format
-			printer := history.NewPrinter(pinnedItems)
-			printer.PrintItems()
-		} else {
-			// Print in JSON format (default)
-			jsonData, err := json.MarshalIndent(pinnedItems, "", "  ")
-			if err != nil {
-				cmd.PrintErrln("Error formatting JSON:", err)
-				return
-			}
-			fmt.Println(string(jsonData))
+		if err := printPage(pinnedItems, format, filter); err != nil {
+			cmd.PrintErrln("Error printing items:", err)
 		}
 	},
 }
 
 func init() {
 	pinsCmd.Flags().BoolVarP(&tableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	pinsCmd.Flags().StringVarP(&pinsFormat, "format", "f", string(output.FormatJSON), "Output format: "+output.FormatNames())
+	pinsCmd.Flags().IntVarP(&pinsLimit, "limit", "l", 0, "Limit the number of items to display (0 for no limit)")
+	addWhereFlag(pinsCmd)
+	addPageFlags(pinsCmd)
+	addFieldFlags(pinsCmd)
+	addRedactFlags(pinsCmd)
+	addSourceFlags(pinsCmd)
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gkwa/sunlitsparrow/internal/snippets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pinsSyncPrune bool
+	pinsSyncApply bool
+	pinsSyncForce bool
+)
+
+// pinsSyncCmd represents the pins sync command
+var pinsSyncCmd = &cobra.Command{
+	Use:   "sync <snippets.yaml>",
+	Short: "Reconcile pinned items with a snippets file",
+	Long: `Reconcile pinned items with a YAML or JSON file declaring pin keys,
+titles and texts:
+
+  snippets:
+    - pin: s
+      title: Signature
+      text: |
+        Kind regards,
+        Jane
+
+Missing snippets are created, changed ones updated, and with --prune pinned
+items not in the file are unpinned. Without --apply only the diff is shown.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		declared, err := snippets.Load(args[0])
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		session, err := openForWrite(cmd.Context(), pinsSyncForce)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		defer session.Close()
+
+		pinned, err := session.repo.GetPinnedItems()
+		if err != nil {
+			cmd.PrintErrln("Error retrieving pinned items:", err)
+			return
+		}
+
+		changes := snippets.Plan(pinned, declared, pinsSyncPrune)
+		if len(changes) == 0 {
+			cmd.Println("Pinned items are already in sync.")
+			return
+		}
+
+		fmt.Print(snippets.FormatDiff(changes))
+		fmt.Println()
+
+		if !pinsSyncApply {
+			cmd.Printf("Dry run: %d changes. Re-run with --apply to make them.\n", len(changes))
+			return
+		}
+
+		if err := session.repo.SyncPins(snippets.PinChanges(changes)); err != nil {
+			cmd.PrintErrln("Error syncing pins, nothing was changed:", err)
+			return
+		}
+		cmd.Printf("Applied %d changes.\n", len(changes))
+	},
+}
+
+func init() {
+	pinsSyncCmd.Flags().BoolVar(&pinsSyncPrune, "prune", false, "Unpin pinned items that are not in the file")
+	pinsSyncCmd.Flags().BoolVar(&pinsSyncApply, "apply", false, "Make the changes instead of only previewing them")
+	pinsSyncCmd.Flags().BoolVar(&pinsSyncForce, "force", false, "Write even if the database appears to be locked")
+
+	pinsCmd.AddCommand(pinsSyncCmd)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/gkwa/sunlitsparrow/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	purgeOlderThan int
+	purgeApps      []string
+	purgeSecrets   bool
+	purgeRulesFile string
+	purgeForce     bool
+	purgePinned    bool
+	purgeApply     bool
+	purgeBackupDir string
+)
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete clipboard items matching a retention policy",
+	Long: `Delete clipboard items older than a number of days, copied from specific
+applications, or containing secrets detected by the scan rules.
+
+Without --apply the command only previews what would be deleted. With
+--apply the database is backed up before the items and their contents are
+deleted in a single transaction. Pinned items are kept unless
+--include-pinned is given. Like the other commands that write, --apply
+refuses to run while another process holds a write lock on the database
+unless --force is given. Quit Maccy before applying, as it may overwrite
+the changes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if purgeOlderThan <= 0 && len(purgeApps) == 0 && !purgeSecrets {
+			cmd.PrintErrln("Error: specify at least one of --older-than, --app or --secrets")
+			return
+		}
+
+		var scanner *secrets.Scanner
+		if purgeSecrets {
+			rules, err := secrets.LoadRules(purgeRulesFile, !cmd.Flags().Changed("rules"))
+			if err != nil {
+				cmd.PrintErrln("Error loading rules:", err)
+				return
+			}
+			scanner = secrets.NewScanner(rules)
+		}
+
+		// A preview only reads; the write lock is only taken to delete
+		var historyRepo *history.Repository
+		var session *writeSession
+		if purgeApply {
+			var err error
+			if session, err = openForWrite(cmd.Context(), purgeForce); err != nil {
+				cmd.PrintErrln("Error:", err)
+				return
+			}
+			defer session.Close()
+			historyRepo = session.repo
+		} else {
+			dbConn, err := openMaccyDB()
+			if err != nil {
+				cmd.PrintErrln("Error opening database:", err)
+				return
+			}
+			defer dbConn.Close()
+			historyRepo = history.NewRepository(dbConn).WithContext(cmd.Context())
+		}
+
+		filter := history.Filter{
+			Applications:  purgeApps,
+			ExcludePinned: !purgePinned,
+		}
+		if purgeOlderThan > 0 {
+			filter.Until = time.Now().AddDate(0, 0, -purgeOlderThan)
+		}
+
+		items, err := historyRepo.GetItems(filter)
+		if err != nil {
+			cmd.PrintErrln("Error retrieving items:", err)
+			return
+		}
+
+		if scanner != nil {
+			matched := items[:0]
+			for _, item := range items {
+				if len(scanner.ScanItem(item)) > 0 {
+					matched = append(matched, item)
+				}
+			}
+			items = matched
+		}
+
+		if len(items) == 0 {
+			cmd.Println("No items match the purge criteria.")
+			return
+		}
+
+		if err := output.WriteItemsTable(os.Stdout, items); err != nil {
+			cmd.PrintErrln("Error printing items:", err)
+			return
+		}
+		cmd.Println()
+
+		if !purgeApply {
+			cmd.Printf("Dry run: %d items would be deleted. Re-run with --apply to delete them.\n", len(items))
+			return
+		}
+
+		backupPath, err := session.backup(purgeBackupDir)
+		if err != nil {
+			cmd.PrintErrln("Error backing up database, nothing was deleted:", err)
+			return
+		}
+		cmd.Printf("Backed up database to %s\n", backupPath)
+
+		ids := make([]int, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+
+		deleted, err := historyRepo.DeleteItems(ids)
+		if err != nil {
+			cmd.PrintErrln("Error deleting items:", err)
+			return
+		}
+		cmd.Printf("Deleted %d items.\n", deleted)
+	},
+}
+
+func init() {
+	purgeCmd.Flags().IntVar(&purgeOlderThan, "older-than", 0, "Delete items last copied more than this many days ago")
+	purgeCmd.Flags().StringSliceVarP(&purgeApps, "app", "a", nil, "Delete items copied from these applications")
+	purgeCmd.Flags().BoolVar(&purgeSecrets, "secrets", false, "Delete items containing secrets found by the scan rules")
+	purgeCmd.Flags().StringVarP(&purgeRulesFile, "rules", "r", secrets.DefaultRulesPath(), "JSON file with additional detection rules")
+	purgeCmd.Flags().BoolVar(&purgePinned, "include-pinned", false, "Also delete pinned items")
+	purgeCmd.Flags().BoolVar(&purgeForce, "force", false, "Write even if the database appears to be locked")
+	purgeCmd.Flags().BoolVar(&purgeApply, "apply", false, "Delete the items instead of only previewing them")
+	purgeCmd.Flags().StringVar(&purgeBackupDir, "backup-dir", "", "Directory for the automatic backup (default: next to the database)")
+}
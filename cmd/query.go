@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/db"
+	"github.com/gkwa/sunlitsparrow/internal/query"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryFormat   string
+	queryRawTimes bool
+	queryDatabase string
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query <SQL>",
+	Short: "Run a read-only SQL query against the database",
+	Long: `Run a single SQL statement against a read-only connection to the Maccy
+database and print the result as a table, JSON, CSV or NDJSON:
+
+  sunlitsparrow query "SELECT ZAPPLICATION, COUNT(*) FROM ZHISTORYITEM GROUP BY 1"
+
+The database is opened read-only with query_only set, and statements other
+than queries are rejected. Core Data stores times as seconds since
+2001-01-01; columns declared as timestamps or named like ZLASTCOPIEDAT,
+*date or *timestamp are converted to readable times unless --raw-times is
+given. Values are printed as stored; --redact does not apply to queries.`,
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := query.ParseFormat(queryFormat)
+		if err != nil {
+			return queryFailed("%w", err)
+		}
+
+		path := queryDatabase
+		if path == "" {
+			if path, err = db.FindMaccyDB(); err != nil {
+				return queryFailed("%w", err)
+			}
+		}
+
+		dbConn, err := db.OpenReadOnly(path)
+		if err != nil {
+			return queryFailed("opening database: %w", err)
+		}
+		defer dbConn.Close()
+
+		result, err := query.Run(dbConn, strings.Join(args, " "), query.Options{RawTimes: queryRawTimes})
+		if err != nil {
+			return queryFailed("running query: %w", err)
+		}
+
+		if err := result.Write(os.Stdout, format); err != nil {
+			return queryFailed("writing results: %w", err)
+		}
+		return nil
+	},
+}
+
+// queryFailed makes query exit with status 1, so scripts notice rejected
+// or failing statements
+func queryFailed(format string, args ...interface{}) error {
+	return &exitStatus{code: 1, err: fmt.Errorf(format, args...)}
+}
+
+func init() {
+	queryCmd.Flags().StringVarP(&queryFormat, "format", "f", string(query.FormatTable), "Output format: table, json, csv or ndjson")
+	queryCmd.Flags().BoolVar(&queryRawTimes, "raw-times", false, "Print Cocoa timestamps as stored instead of converting them")
+	queryCmd.Flags().StringVar(&queryDatabase, "db", "", "Query this database file instead of the Maccy database")
+}
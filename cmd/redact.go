@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/redact"
+	"github.com/gkwa/sunlitsparrow/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redactKinds    []string
+	redactPatterns []string
+)
+
+// addRedactFlags registers the redaction flags on a command that prints or
+// exports history items
+func addRedactFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&redactKinds, "redact", nil, "Mask sensitive data: all, secrets, emails, phones, cards")
+	cmd.Flags().StringArrayVar(&redactPatterns, "redact-pattern", nil, "Additional regular expression to mask (repeatable)")
+}
+
+// redactionEnabled reports whether any redaction flag was given
+func redactionEnabled() bool {
+	return len(redactKinds) > 0 || len(redactPatterns) > 0
+}
+
+// newRedactor builds a redactor from the redaction flags, or returns nil when
+// redaction was not requested
+func newRedactor() (*redact.Redactor, error) {
+	if !redactionEnabled() {
+		return nil, nil
+	}
+
+	rules, err := secrets.LoadRules(secrets.DefaultRulesPath(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return redact.NewRedactor(redact.Options{
+		Kinds:    redactKinds,
+		Patterns: redactPatterns,
+		Rules:    rules,
+	})
+}
+
+// redactItems applies the redaction flags to items before they are printed
+func redactItems(items []history.HistoryItem) ([]history.HistoryItem, []redact.Record, error) {
+	redactor, err := newRedactor()
+	if err != nil || redactor == nil {
+		return items, nil, err
+	}
+
+	redacted, records := redactor.RedactItems(items)
+	return redacted, records, nil
+}
+
+// redactPreview masks a preview derived from unredacted items
+func redactPreview(redactor *redact.Redactor, preview string) string {
+	if redactor == nil {
+		return preview
+	}
+	return redactor.RedactText(preview)
+}
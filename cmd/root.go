@@ -1,28 +1,82 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/gkwa/sunlitsparrow/internal/logger"
 	"github.com/spf13/cobra"
 )
 
-var verbosity int
+var (
+	verbosity       int
+	logFormat       string
+	logFile         string
+	skipLayoutCheck bool
+
+	// logCloser releases the --log-file once the command has run
+	logCloser io.Closer
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "sunlitsparrow",
 	Short: "Explore and query Maccy's clipboard history database",
 	Long:  `A tool to explore and query the SQLite database used by Maccy to store clipboard history.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		logger.SetLogLevel(verbosity)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		format, err := logger.ParseFormat(logFormat)
+		if err != nil {
+			return err
+		}
+		if logCloser, err = logger.Setup(logger.Options{Verbosity: verbosity, Format: format, File: logFile}); err != nil {
+			return err
+		}
+
+		// Records logged while running the command, including the queries
+		// traced by repositories it opens, name the command
+		command := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+		cmd.SetContext(logger.NewContext(cmd.Context(), logger.Default().With("command", command)))
+		return nil
 	},
 }
 
+// exitStatus is returned by commands that exit with a specific status.
+// Commands returning it set SilenceErrors; err, if any, is printed here.
+type exitStatus struct {
+	code int
+	err  error
+}
+
+func (e *exitStatus) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitStatus) Unwrap() error {
+	return e.err
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	// Closed here rather than in a post-run hook, which cobra skips when a
+	// command returns an error
+	if logCloser != nil {
+		logCloser.Close()
+	}
+	if err != nil {
+		var status *exitStatus
+		if errors.As(err, &status) {
+			if status.err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", status.err)
+			}
+			os.Exit(status.code)
+		}
 		fmt.Fprintf(os.Stderr, "Error executing command: %v\n", err)
 		os.Exit(1)
 	}
@@ -30,10 +84,27 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "increase verbosity level")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", string(logger.FormatText), "log record format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "append log records to this file instead of stderr (logs info without -v)")
+	rootCmd.PersistentFlags().BoolVar(&skipLayoutCheck, "skip-layout-check", false, "don't warn about unrecognized database layouts")
 
 	// Add subcommands
 	rootCmd.AddCommand(schemaCmd)
 	rootCmd.AddCommand(itemsCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(pinsCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+	rootCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(browseCmd)
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(purgeCmd)
+	rootCmd.AddCommand(dedupeCmd)
+	rootCmd.AddCommand(clustersCmd)
+	rootCmd.AddCommand(dumpCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(serveCmd)
 }
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/secrets"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+	"github.com/spf13/cobra"
+)
+
+// errSecretsFound makes scan exit with status 1 after reporting findings
+var errSecretsFound = &exitStatus{code: 1}
+
+// scanFailed makes scan exit with status 2, so a failed scan is never
+// mistaken for a clean one
+func scanFailed(format string, args ...interface{}) error {
+	return &exitStatus{code: 2, err: fmt.Errorf(format, args...)}
+}
+
+var (
+	scanTableFormat bool
+	scanRulesFile   string
+)
+
+// scanCmd represents the scan command
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan clipboard history for secrets and credentials",
+	Long: `Scan the decoded text contents of all clipboard items for API keys,
+tokens, private keys and passwords. --where limits the scan to matching items.
+
+Built-in rules can be extended or overridden with a JSON rules file. The
+command exits with status 1 when any finding is reported and with status 2
+when the scan itself fails.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		where, err := parseWhere()
+		if err != nil {
+			return scanFailed("%w", err)
+		}
+
+		rulesPath := scanRulesFile
+		optional := !cmd.Flags().Changed("rules")
+		rules, err := secrets.LoadRules(rulesPath, optional)
+		if err != nil {
+			return scanFailed("loading rules: %w", err)
+		}
+
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			return scanFailed("opening database: %w", err)
+		}
+		defer dbConn.Close()
+
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		items, err := historyRepo.GetItems(history.Filter{Where: where})
+		if err != nil {
+			return scanFailed("retrieving items: %w", err)
+		}
+
+		scanner := secrets.NewScanner(rules)
+		findings := []secrets.Finding{}
+		for _, item := range items {
+			findings = append(findings, scanner.ScanItem(item)...)
+		}
+
+		if scanTableFormat {
+			printFindings(findings)
+		} else {
+			jsonData, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				return scanFailed("formatting JSON: %w", err)
+			}
+			fmt.Println(string(jsonData))
+		}
+
+		if len(findings) > 0 {
+			return errSecretsFound
+		}
+		return nil
+	},
+}
+
+// printFindings prints scan findings in a table
+func printFindings(findings []secrets.Finding) {
+	if len(findings) == 0 {
+		fmt.Println("No secrets found.")
+		return
+	}
+
+	t := table.New(
+		table.Column{Header: "ID", Align: table.AlignRight},
+		table.Column{Header: "Application", Max: 40, Flex: true},
+		table.Column{Header: "Rule", Flex: true},
+		table.Column{Header: "Excerpt", Max: 60, Flex: true},
+	)
+	for _, finding := range findings {
+		appStr := finding.Application
+		if appStr == "" {
+			appStr = "<unknown>"
+		}
+		t.Append(fmt.Sprint(finding.ItemID), appStr, finding.Rule, finding.Excerpt)
+	}
+	t.Render(os.Stdout)
+}
+
+func init() {
+	scanCmd.Flags().BoolVarP(&scanTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	scanCmd.Flags().StringVarP(&scanRulesFile, "rules", "r", secrets.DefaultRulesPath(), "JSON file with additional detection rules")
+	addWhereFlag(scanCmd)
+}
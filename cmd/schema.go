@@ -1,19 +1,32 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/gkwa/sunlitsparrow/internal/db"
 	"github.com/gkwa/sunlitsparrow/internal/schema"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
-var outputFile string
+var (
+	outputFile           string
+	schemaFormat         string
+	diagramFormat        string
+	diagramNoBookkeeping bool
+)
 
 // schemaCmd represents the schema command
 var schemaCmd = &cobra.Command{
 	Use:   "schema",
 	Short: "Show database schema",
+	Long: `Show the tables, columns, primary and foreign keys, indexes, views and
+triggers of the Maccy database as text, JSON or YAML.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		dbConn, err := db.OpenMaccyDB()
+		dbConn, err := openMaccyDB()
 		if err != nil {
 			cmd.PrintErrln("Error opening database:", err)
 			return
@@ -31,10 +44,244 @@ var schemaCmd = &cobra.Command{
 			return
 		}
 
-		explorer.ExploreSchema()
+		s, err := explorer.Schema()
+		if err != nil {
+			cmd.PrintErrln("Error reading schema:", err)
+			return
+		}
+
+		if err := writeSchemaValue(os.Stdout, s, schemaFormat, func(w io.Writer) error {
+			return schema.WriteText(w, s)
+		}); err != nil {
+			cmd.PrintErrln("Error:", err)
+		}
 	},
 }
 
+// schemaDiffCmd represents the schema diff command
+var schemaDiffCmd = &cobra.Command{
+	Use:   "diff OLD.sqlite NEW.sqlite",
+	Short: "Compare the schemas of two databases",
+	Long: `Report tables, columns, indexes, foreign keys, views and triggers that
+were added, removed or changed between two databases, for example two
+versions of Maccy's storage. Exits with status 1 when the schemas differ and
+with status 2 when they cannot be compared.`,
+	Args:          cobra.ExactArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schemas := make([]*schema.Schema, len(args))
+		for i, path := range args {
+			s, err := readSchema(path)
+			if err != nil {
+				return &exitStatus{code: 2, err: fmt.Errorf("reading schema of %s: %w", path, err)}
+			}
+			schemas[i] = s
+		}
+
+		diff := schema.Compare(schemas[0], schemas[1])
+
+		if err := writeSchemaValue(os.Stdout, diff, schemaFormat, func(w io.Writer) error {
+			if diff.Empty() {
+				_, err := fmt.Fprintln(w, "Schemas are identical.")
+				return err
+			}
+			return schema.WriteDiffText(w, diff)
+		}); err != nil {
+			return &exitStatus{code: 2, err: err}
+		}
+
+		if !diff.Empty() {
+			return &exitStatus{code: 1}
+		}
+		return nil
+	},
+}
+
+// schemaVersionCmd represents the schema version command
+var schemaVersionCmd = &cobra.Command{
+	Use:   "version [DATABASE]",
+	Short: "Identify the storage layout of the database",
+	Long: `Fingerprint the layout of the Maccy database (or the given database file)
+from its tables and columns, Core Data store metadata and SwiftData markers,
+and report the range of Maccy releases writing that kind of layout: the Core
+Data layout of 0.x to 1.x or the SwiftData layout of 2.0 and later. The hash
+identifies the exact layout for bug reports and is not mapped to specific
+releases. Exits with status 1 when the layout is not recognized and with
+status 2 when the database cannot be read.`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := databasePath(args)
+		if err != nil {
+			return &exitStatus{code: 2, err: err}
+		}
+
+		dbConn, err := db.OpenReadOnly(path)
+		if err != nil {
+			return &exitStatus{code: 2, err: fmt.Errorf("opening database: %w", err)}
+		}
+		defer dbConn.Close()
+
+		fp, err := schema.NewExplorer(dbConn).Detect()
+		if err != nil {
+			return &exitStatus{code: 2, err: fmt.Errorf("detecting layout: %w", err)}
+		}
+
+		if err := writeSchemaValue(os.Stdout, fp, schemaFormat, func(w io.Writer) error {
+			return schema.WriteFingerprintText(w, fp)
+		}); err != nil {
+			return &exitStatus{code: 2, err: err}
+		}
+
+		if !fp.Recognized {
+			return &exitStatus{code: 1}
+		}
+		return nil
+	},
+}
+
+// schemaCoreDataCmd represents the schema coredata command
+var schemaCoreDataCmd = &cobra.Command{
+	Use:   "coredata [DATABASE]",
+	Short: "Show Core Data store metadata",
+	Long: `Decode the Core Data store metadata of the Maccy database (or the given
+database file): the store version and UUID, the model version identifiers
+and entity version hashes from the binary property list in Z_METADATA, the
+primary key counters of Z_PRIMARYKEY and the size of the cached model.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := databasePath(args)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		dbConn, err := db.OpenReadOnly(path)
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		meta, err := schema.NewExplorer(dbConn).CoreData()
+		if err != nil {
+			cmd.PrintErrln("Error reading Core Data metadata:", err)
+			return
+		}
+
+		if err := writeSchemaValue(os.Stdout, meta, schemaFormat, func(w io.Writer) error {
+			return schema.WriteCoreDataText(w, meta)
+		}); err != nil {
+			cmd.PrintErrln("Error:", err)
+		}
+	},
+}
+
+// schemaDiagramCmd represents the schema diagram command
+var schemaDiagramCmd = &cobra.Command{
+	Use:   "diagram [DATABASE]",
+	Short: "Draw an entity-relationship diagram of the database",
+	Long: `Write an entity-relationship diagram of the Maccy database (or the given
+database file) as a Mermaid erDiagram or a Graphviz DOT graph:
+
+  sunlitsparrow schema diagram -f dot | dot -Tsvg > schema.svg
+
+Core Data does not declare foreign keys, so relations are also inferred from
+integer columns such as ZITEM and drawn dashed. --no-bookkeeping hides the
+Z_ENT and Z_OPT columns and the store metadata tables.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var s *schema.Schema
+		var err error
+		if len(args) == 1 {
+			s, err = readSchema(args[0])
+		} else {
+			s, err = readMaccySchema()
+		}
+		if err != nil {
+			cmd.PrintErrln("Error reading schema:", err)
+			return
+		}
+
+		opts := schema.DiagramOptions{HideBookkeeping: diagramNoBookkeeping}
+		switch diagramFormat {
+		case "mermaid":
+			err = schema.WriteMermaid(os.Stdout, s, opts)
+		case "dot":
+			err = schema.WriteDOT(os.Stdout, s, opts)
+		default:
+			err = fmt.Errorf("unknown diagram format %q (expected mermaid or dot)", diagramFormat)
+		}
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+		}
+	},
+}
+
+// databasePath returns the database file given as the only argument, or the
+// Maccy database
+func databasePath(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	return db.FindMaccyDB()
+}
+
+// readMaccySchema reads the schema of the Maccy database
+func readMaccySchema() (*schema.Schema, error) {
+	dbConn, err := openMaccyDB()
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	return schema.NewExplorer(dbConn).Schema()
+}
+
+// readSchema reads the schema of the database at path without modifying it
+func readSchema(path string) (*schema.Schema, error) {
+	dbConn, err := db.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	return schema.NewExplorer(dbConn).Schema()
+}
+
+// writeSchemaValue writes v as JSON or YAML, or calls text for the text format
+func writeSchemaValue(w io.Writer, v interface{}, format string, text func(io.Writer) error) error {
+	switch format {
+	case "text":
+		return text(w)
+	case "json":
+		jsonData, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error formatting JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Errorf("error formatting YAML: %w", err)
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unknown format %q (expected text, json or yaml)", format)
+}
+
 func init() {
 	schemaCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output schema to SQLite-compatible file")
+	schemaCmd.PersistentFlags().StringVarP(&schemaFormat, "format", "f", "text", "Output format: text, json or yaml")
+	schemaCmd.AddCommand(schemaDiffCmd)
+	schemaCmd.AddCommand(schemaVersionCmd)
+	schemaCmd.AddCommand(schemaCoreDataCmd)
+
+	schemaDiagramCmd.Flags().StringVarP(&diagramFormat, "format", "f", "mermaid", "Diagram format: mermaid or dot")
+	schemaDiagramCmd.Flags().BoolVar(&diagramNoBookkeeping, "no-bookkeeping", false, "Hide Core Data bookkeeping columns and metadata tables")
+	schemaCmd.AddCommand(schemaDiagramCmd)
 }
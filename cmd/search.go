@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchTableFormat bool
+	searchFormat      string
+	searchLimit       int
+	searchApps        []string
+	searchPinned      bool
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Search clipboard items by title and text content",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat(searchFormat, searchTableFormat)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		filter := history.Filter{
+			Text:         strings.Join(args, " "),
+			Applications: searchApps,
+			PinnedOnly:   searchPinned,
+			Where:        where,
+			Limit:        searchLimit,
+		}
+		if err := applyPageFlags(&filter); err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		items, err := loadItems(cmd.Context(), filter, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			return repo.GetItems(filter)
+		})
+		if err != nil {
+			cmd.PrintErrln("Error searching items:", err)
+			return
+		}
+
+		items, _, err = redactItems(items)
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		if len(items) == 0 && !isMachineFormat(format) {
+			cmd.Println("No items found.")
+			return
+		}
+
+		if err := printPage(items, format, filter); err != nil {
+			cmd.PrintErrln("Error printing items:", err)
+		}
+	},
+}
+
+func init() {
+	searchCmd.Flags().BoolVarP(&searchTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	searchCmd.Flags().StringVarP(&searchFormat, "format", "f", string(output.FormatJSON), "Output format: "+output.FormatNames())
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 0, "Limit the number of items to display (0 for no limit)")
+	searchCmd.Flags().StringSliceVarP(&searchApps, "app", "a", nil, "Only include items copied from these applications")
+	searchCmd.Flags().BoolVarP(&searchPinned, "pinned", "p", false, "Only include pinned items")
+	addWhereFlag(searchCmd)
+	addPageFlags(searchCmd)
+	addFieldFlags(searchCmd)
+	addRedactFlags(searchCmd)
+	addSourceFlags(searchCmd)
+}
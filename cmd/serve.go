@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/schema"
+	"github.com/gkwa/sunlitsparrow/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr  string
+	serveToken string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve clipboard history over a local HTTP JSON API",
+	Run: func(cmd *cobra.Command, args []string) {
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		token := serveToken
+		if token == "" {
+			token = os.Getenv("SUNLITSPARROW_TOKEN")
+		}
+
+		redactor, err := newRedactor()
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		explorer := schema.NewExplorer(dbConn)
+		apiServer := server.NewServer(historyRepo, explorer, token)
+		apiServer.SetRedactor(redactor)
+		apiServer.AllowHost(serveAddr)
+
+		httpServer := &http.Server{
+			Addr:              serveAddr,
+			Handler:           apiServer,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		cmd.Printf("Listening on http://%s\n", serveAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			cmd.PrintErrln("Error serving HTTP:", err)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveAddr, "addr", "a", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Require this bearer token (default: $SUNLITSPARROW_TOKEN)")
+	addRedactFlags(serveCmd)
+}
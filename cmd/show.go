@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showDetails bool
+	showRaw     bool
+	showType    string
+	showFormat  string
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <id>...",
+	Short: "Show clipboard items by ID",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := parseStructuredFormat(showFormat)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+		binary, err := output.ParseBinaryMode(binarySpec, format)
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		ids := make([]int, 0, len(args))
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				cmd.PrintErrln("Invalid item ID:", arg)
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		items, err := loadItems(cmd.Context(), history.Filter{}, func(repo *history.Repository) ([]history.HistoryItem, error) {
+			items := make([]history.HistoryItem, 0, len(ids))
+			for _, id := range ids {
+				item, err := repo.GetItem(id)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return items, nil
+		})
+		if err != nil {
+			cmd.PrintErrln("Error retrieving item:", err)
+			return
+		}
+		if fromFile != "" {
+			if items, err = selectByID(items, ids); err != nil {
+				cmd.PrintErrln("Error retrieving item:", err)
+				return
+			}
+		}
+
+		items, _, err = redactItems(items)
+		if err != nil {
+			cmd.PrintErrln("Error configuring redaction:", err)
+			return
+		}
+
+		if showRaw || showType != "" {
+			for _, item := range items {
+				if err := writeRaw(item, showType); err != nil {
+					cmd.PrintErrln("Error:", err)
+					return
+				}
+			}
+			return
+		}
+
+		if showDetails {
+			printer := history.NewPrinter(items)
+			printer.PrintDetails()
+		} else if err := output.EncodeItems(os.Stdout, format, items, nil, binary); err != nil {
+			cmd.PrintErrln("Error:", err)
+		}
+	},
+}
+
+func init() {
+	showCmd.Flags().BoolVarP(&showDetails, "details", "d", false, "Display fields and decoded contents instead of JSON")
+	showCmd.Flags().BoolVarP(&showRaw, "raw", "r", false, "Print only the decoded text, e.g. for fzf previews")
+	showCmd.Flags().StringVar(&showType, "type", "", "Print the raw bytes of the content with this pasteboard type")
+	showCmd.Flags().StringVarP(&showFormat, "format", "f", string(output.FormatJSON), "Output format: json or yaml")
+	addBinaryFlag(showCmd)
+	addRedactFlags(showCmd)
+	addSourceFlags(showCmd)
+}
+
+// selectByID picks the items with the given IDs, in order
+func selectByID(items []history.HistoryItem, ids []int) ([]history.HistoryItem, error) {
+	byID := make(map[int]history.HistoryItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	selected := make([]history.HistoryItem, 0, len(ids))
+	for _, id := range ids {
+		item, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", history.ErrItemNotFound, id)
+		}
+		selected = append(selected, item)
+	}
+	return selected, nil
+}
+
+// writeRaw prints the item's decoded text, or the raw bytes of one content
+// type when contentType is set
+func writeRaw(item history.HistoryItem, contentType string) error {
+	if contentType != "" {
+		for _, content := range item.Contents {
+			if content.Type == contentType {
+				_, err := os.Stdout.Write(content.Value)
+				return err
+			}
+		}
+		return fmt.Errorf("item %d has no %s content", item.ID, contentType)
+	}
+
+	text := item.DecodedText()
+	if text == "" && len(item.Contents) > 0 {
+		text = item.Contents[0].DecodedText()
+	}
+	_, err := fmt.Fprintln(os.Stdout, text)
+	return err
+}
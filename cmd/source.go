@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gkwa/sunlitsparrow/internal/crypt"
+	"github.com/gkwa/sunlitsparrow/internal/db"
+	"github.com/gkwa/sunlitsparrow/internal/export"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+	"github.com/gkwa/sunlitsparrow/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var fromFile string
+
+// addSourceFlags lets a read command load items from an export file,
+// encrypted or not, instead of the Maccy database
+func addSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&fromFile, "from", "", "Read items from an export file instead of the Maccy database")
+	addKeyFlags(cmd)
+}
+
+// loadItems returns the items selected by filter from the --from export file
+// when given, otherwise by running query against the Maccy database with
+// a repository logging to the logger carried by ctx
+func loadItems(ctx context.Context, filter history.Filter, query func(*history.Repository) ([]history.HistoryItem, error)) ([]history.HistoryItem, error) {
+	if noContents {
+		if filter.Where != nil && filter.Where.UsesContents() {
+			return nil, fmt.Errorf("--where matches on text or types, which needs contents; drop --no-contents")
+		}
+		if filter.Sort.Field == history.SortSize {
+			return nil, fmt.Errorf("sorting by size needs contents; drop --no-contents")
+		}
+	}
+
+	if fromFile != "" {
+		doc, err := export.ReadFile(fromFile, func() (crypt.Key, error) {
+			return readKey(false)
+		})
+		if err != nil {
+			return nil, err
+		}
+		items, err := filter.Apply(doc.Items)
+		if err != nil {
+			return nil, err
+		}
+		if noContents {
+			for i := range items {
+				items[i].Contents = nil
+			}
+		}
+		return items, nil
+	}
+
+	dbConn, err := openMaccyDB()
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	defer dbConn.Close()
+
+	repo := history.NewRepository(dbConn).WithContext(ctx)
+	repo.SkipContents(noContents)
+	return query(repo)
+}
+
+// openMaccyDB opens the Maccy database and, unless --skip-layout-check is
+// given, warns on the command's error output when its layout is not
+// recognized, so scripts notice when a Maccy update changed the storage
+func openMaccyDB() (*sql.DB, error) {
+	dbConn, err := db.OpenMaccyDB()
+	if err != nil || skipLayoutCheck {
+		return dbConn, err
+	}
+
+	fp, err := schema.NewExplorer(dbConn).CheckLayout()
+	if err != nil {
+		logger.Debug("Error checking database layout", "error", err)
+		return dbConn, nil
+	}
+	logger.Info("Checked database layout", "layout", fp.Layout, "releases", fp.Releases)
+	if warning := fp.Warning(); warning != "" {
+		rootCmd.PrintErrln("Warning:", warning)
+	}
+	return dbConn, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/output"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsTableFormat bool
+	statsFormat      string
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize clipboard history by application and content type",
+	Long: `Count items, pins and copies and break them down by application and
+content type. With --where the statistics cover only the matching items.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var format output.Format
+		if !statsTableFormat {
+			var err error
+			if format, err = parseStructuredFormat(statsFormat); err != nil {
+				cmd.PrintErrln("Error:", err)
+				return
+			}
+		}
+
+		where, err := parseWhere()
+		if err != nil {
+			cmd.PrintErrln("Error:", err)
+			return
+		}
+
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		var stats *history.Stats
+		if where != nil {
+			// Filtered statistics are computed from the matching items
+			var items []history.HistoryItem
+			if items, err = historyRepo.GetItems(history.Filter{Where: where}); err == nil {
+				stats = history.Summarize(items)
+			}
+		} else {
+			stats, err = historyRepo.GetStats()
+		}
+		if err != nil {
+			cmd.PrintErrln("Error computing statistics:", err)
+			return
+		}
+
+		if statsTableFormat {
+			printStats(stats)
+			return
+		}
+
+		if stats.Applications == nil {
+			stats.Applications = []history.ApplicationStat{}
+		}
+		if stats.ContentTypes == nil {
+			stats.ContentTypes = []history.ContentTypeStat{}
+		}
+		if err := output.Encode(os.Stdout, format, stats); err != nil {
+			cmd.PrintErrln("Error:", err)
+		}
+	},
+}
+
+// printStats prints the totals followed by application and content type
+// tables
+func printStats(stats *history.Stats) {
+	fmt.Printf("Items:  %d (%d pinned)\n", stats.TotalItems, stats.PinnedItems)
+	fmt.Printf("Copies: %d\n", stats.TotalCopies)
+	if stats.TotalItems > 0 {
+		fmt.Printf("Oldest: %s\n", history.FormatTime(stats.OldestItem))
+		fmt.Printf("Newest: %s\n", history.FormatTime(stats.NewestItem))
+	}
+
+	if len(stats.Applications) > 0 {
+		t := table.New(
+			table.Column{Header: "Application", Max: 60, Flex: true},
+			table.Column{Header: "Items", Align: table.AlignRight},
+			table.Column{Header: "Copies", Align: table.AlignRight},
+		)
+		for _, app := range stats.Applications {
+			name := app.Application
+			if name == "" {
+				name = "(unknown)"
+			}
+			t.Append(name, strconv.Itoa(app.Items), strconv.Itoa(app.Copies))
+		}
+		fmt.Println()
+		t.Render(os.Stdout)
+	}
+
+	if len(stats.ContentTypes) > 0 {
+		t := table.New(
+			table.Column{Header: "Content Type", Max: 60, Flex: true},
+			table.Column{Header: "Count", Align: table.AlignRight},
+			table.Column{Header: "Size", Align: table.AlignRight},
+		)
+		for _, contentType := range stats.ContentTypes {
+			t.Append(contentType.Type, strconv.Itoa(contentType.Count), output.HumanBytes(int(contentType.Bytes)))
+		}
+		fmt.Println()
+		t.Render(os.Stdout)
+	}
+}
+
+func init() {
+	statsCmd.Flags().BoolVarP(&statsTableFormat, "table", "t", false, "Display output in table format instead of JSON")
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", string(output.FormatJSON), "Output format: json or yaml")
+	addWhereFlag(statsCmd)
+}
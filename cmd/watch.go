@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/hooks"
+	"github.com/gkwa/sunlitsparrow/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval  time.Duration
+	watchHooksFile string
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch for new clipboard items and run hooks",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := hooks.LoadConfig(watchHooksFile)
+		if err != nil {
+			cmd.PrintErrln("Error loading hooks:", err)
+			return
+		}
+
+		dbConn, err := openMaccyDB()
+		if err != nil {
+			cmd.PrintErrln("Error opening database:", err)
+			return
+		}
+		defer dbConn.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		historyRepo := history.NewRepository(dbConn).WithContext(cmd.Context())
+		runner := hooks.NewRunner(config)
+		watcher := watch.NewWatcher(historyRepo, watchInterval)
+
+		err = watcher.Run(ctx, func(item history.HistoryItem) {
+			runner.Dispatch(ctx, item)
+		})
+		runner.Wait()
+		if err != nil {
+			cmd.PrintErrln("Error watching database:", err)
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVarP(&watchInterval, "interval", "i", 2*time.Second, "Polling interval")
+	watchCmd.Flags().StringVar(&watchHooksFile, "hooks", hooks.DefaultConfigPath(), "Path to the hooks configuration file")
+}
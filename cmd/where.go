@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var whereExpr string
+
+// addWhereFlag lets a command select items with a filter expression
+func addWhereFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&whereExpr, "where", "w", "",
+		`Only include items matching a filter expression, e.g. 'app == "com.apple.Safari" && age < 7d'`)
+}
+
+// parseWhere parses the --where expression, returning nil if none was given
+func parseWhere() (*history.Expr, error) {
+	if whereExpr == "" {
+		return nil, nil
+	}
+	expr, err := history.ParseExpr(whereExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where expression: %w", err)
+	}
+	return expr, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gkwa/sunlitsparrow/internal/db"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// writeSession is a Maccy database opened for modification
+type writeSession struct {
+	conn *sql.DB
+	path string
+	repo *history.Repository
+}
+
+// openForWrite opens the Maccy database for modification, refusing when
+// another process holds a write lock unless force is set
+func openForWrite(ctx context.Context, force bool) (*writeSession, error) {
+	path, err := db.FindMaccyDB()
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	if err := db.CheckWriteLock(conn); err != nil {
+		if !force || !errors.Is(err, db.ErrLocked) {
+			conn.Close()
+			return nil, fmt.Errorf("%w; use --force to write anyway", err)
+		}
+	}
+
+	return &writeSession{conn: conn, path: path, repo: history.NewRepository(conn).WithContext(ctx)}, nil
+}
+
+// backup copies the database into dir before it is modified
+func (s *writeSession) backup(dir string) (string, error) {
+	return db.Backup(s.conn, s.path, dir)
+}
+
+// Close closes the database connection
+func (s *writeSession) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,240 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/dedupe"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// DefaultThreshold returns the similarity above which two items are
+// clustered by the method. SimHash similarity is the share of equal
+// fingerprint bits, about half of which unrelated texts already share, so
+// it needs a much higher threshold than the Jaccard estimate of MinHash.
+func (m Method) DefaultThreshold() float64 {
+	if m == MethodSimHash {
+		return 0.9
+	}
+	return 0.7
+}
+
+// Options controls clustering
+type Options struct {
+	Method    Method
+	Threshold float64
+	MinSize   int
+}
+
+// ParseMethod validates a method name
+func ParseMethod(name string) (Method, error) {
+	switch Method(strings.ToLower(name)) {
+	case MethodMinHash:
+		return MethodMinHash, nil
+	case MethodSimHash:
+		return MethodSimHash, nil
+	}
+	return "", fmt.Errorf("unknown clustering method %q (expected minhash or simhash)", name)
+}
+
+// Cluster is a set of items with similar text
+type Cluster struct {
+	ID             int                   `json:"id"`
+	Size           int                   `json:"size"`
+	Representative int                   `json:"representative"`
+	Similarity     float64               `json:"similarity"`
+	TotalCopies    int                   `json:"totalCopies"`
+	FirstCopiedAt  time.Time             `json:"firstCopiedAt"`
+	LastCopiedAt   time.Time             `json:"lastCopiedAt"`
+	Span           string                `json:"span"`
+	Items          []history.HistoryItem `json:"-"`
+	ItemIDs        []int                 `json:"itemIds"`
+	Applications   []string              `json:"applications,omitempty"`
+	Preview        string                `json:"preview"`
+}
+
+// entry is an item with a text signature
+type entry struct {
+	item history.HistoryItem
+	sig  signature
+}
+
+// FindClusters groups items whose decoded text is similar, largest clusters
+// first. Items without text are ignored. Each cluster is represented by its
+// most recently copied item, the latest revision of the text.
+func FindClusters(items []history.HistoryItem, opts Options) []Cluster {
+	if opts.Threshold <= 0 {
+		opts.Threshold = opts.Method.DefaultThreshold()
+	}
+	if opts.MinSize < 2 {
+		opts.MinSize = 2
+	}
+
+	var entries []entry
+	for _, item := range items {
+		text := dedupe.NormalizeText(item.DecodedText())
+		if text == "" {
+			continue
+		}
+		sh := shingles(text)
+		var sig signature
+		if opts.Method == MethodSimHash {
+			sig = newSimHash(sh)
+		} else {
+			sig = newMinHash(sh)
+		}
+		entries = append(entries, entry{item: item, sig: sig})
+	}
+
+	sets := newUnionFind(len(entries))
+	forEachCandidate(entries, opts.Method, func(i, j int) {
+		if entries[i].sig.similarity(entries[j].sig) >= opts.Threshold {
+			sets.union(i, j)
+		}
+	})
+
+	members := map[int][]int{}
+	var roots []int
+	for i := range entries {
+		root := sets.find(i)
+		if _, seen := members[root]; !seen {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	var clusters []Cluster
+	for _, root := range roots {
+		if len(members[root]) < opts.MinSize {
+			continue
+		}
+		clusters = append(clusters, newCluster(entries, members[root]))
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if clusters[i].Size != clusters[j].Size {
+			return clusters[i].Size > clusters[j].Size
+		}
+		return clusters[i].LastCopiedAt.After(clusters[j].LastCopiedAt)
+	})
+	for i := range clusters {
+		clusters[i].ID = i + 1
+	}
+	return clusters
+}
+
+// forEachCandidate calls visit for every index pair worth comparing: those
+// sharing a MinHash band, or every pair for SimHash
+func forEachCandidate(entries []entry, method Method, visit func(i, j int)) {
+	if method == MethodSimHash {
+		for i := range entries {
+			for j := i + 1; j < len(entries); j++ {
+				visit(i, j)
+			}
+		}
+		return
+	}
+
+	seen := map[[2]int]bool{}
+	buckets := map[[2]uint64][]int{}
+	for i, e := range entries {
+		for band, h := range e.sig.(*minHash).bands() {
+			key := [2]uint64{uint64(band), h}
+			for _, j := range buckets[key] {
+				pair := [2]int{j, i}
+				if !seen[pair] {
+					seen[pair] = true
+					visit(j, i)
+				}
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+}
+
+func newCluster(entries []entry, indexes []int) Cluster {
+	rep := indexes[0]
+	for _, i := range indexes {
+		if entries[i].item.LastCopiedAt.After(entries[rep].item.LastCopiedAt) {
+			rep = i
+		}
+	}
+
+	cluster := Cluster{
+		Size:           len(indexes),
+		Representative: entries[rep].item.ID,
+		Preview:        strings.Join(strings.Fields(entries[rep].item.Title), " "),
+	}
+
+	apps := map[string]bool{}
+	similarity := 0.0
+	for _, i := range indexes {
+		item := entries[i].item
+		cluster.Items = append(cluster.Items, item)
+		cluster.ItemIDs = append(cluster.ItemIDs, item.ID)
+		cluster.TotalCopies += item.NumberOfCopies
+		if cluster.FirstCopiedAt.IsZero() || (!item.FirstCopiedAt.IsZero() && item.FirstCopiedAt.Before(cluster.FirstCopiedAt)) {
+			cluster.FirstCopiedAt = item.FirstCopiedAt
+		}
+		if item.LastCopiedAt.After(cluster.LastCopiedAt) {
+			cluster.LastCopiedAt = item.LastCopiedAt
+		}
+		if item.Application != "" && !apps[item.Application] {
+			apps[item.Application] = true
+			cluster.Applications = append(cluster.Applications, item.Application)
+		}
+		if i != rep {
+			similarity += entries[i].sig.similarity(entries[rep].sig)
+		}
+	}
+
+	// Average similarity of the other members to the representative
+	cluster.Similarity = float64(int(similarity/float64(len(indexes)-1)*100+0.5)) / 100
+	cluster.Span = formatSpan(cluster.LastCopiedAt.Sub(cluster.FirstCopiedAt))
+	return cluster
+}
+
+// formatSpan formats a duration in the largest whole units, such as "3d 4h"
+// or "12m"
+func formatSpan(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// unionFind tracks which entries have been merged into the same cluster
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	parent := make(unionFind, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return parent
+}
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(i, j int) {
+	if ri, rj := u.find(i), u.find(j); ri != rj {
+		u[rj] = ri
+	}
+}
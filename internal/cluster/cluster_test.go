@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// unrelated are short clipboard texts with nothing in common
+var unrelated = []string{
+	"git status",
+	"ls -la /tmp",
+	"Meeting moved to 3pm",
+	"https://example.com/docs",
+	"kubectl get pods -n prod",
+	"Thanks, see you tomorrow!",
+	"SELECT * FROM users;",
+	"npm install --save-dev",
+	"192.168.1.20",
+	"brew upgrade",
+	"Dear team, the release is out.",
+	"docker compose up -d",
+	"passport renewal form",
+	"grep -rn TODO src",
+	"coffee at 10?",
+	"cd ~/projects/website",
+	"Invoice #4821 attached",
+	"python3 -m venv .venv",
+	"ssh deploy@staging",
+	"Happy birthday, Sam!",
+}
+
+func textItems(texts []string) []history.HistoryItem {
+	now := time.Now()
+	items := make([]history.HistoryItem, len(texts))
+	for i, text := range texts {
+		items[i] = history.HistoryItem{
+			ID:           i + 1,
+			LastCopiedAt: now.Add(-time.Duration(i) * time.Minute),
+			Contents:     []history.Content{{Type: history.ContentTypeText, Value: []byte(text)}},
+		}
+	}
+	return items
+}
+
+// randomTexts returns n short texts of random words, which share no more
+// than chance allows
+func randomTexts(n int) []string {
+	rng := rand.New(rand.NewSource(1))
+	texts := make([]string, n)
+	for i := range texts {
+		b := make([]byte, 12+rng.Intn(20))
+		for j := range b {
+			b[j] = "abcdefghijklmnopqrstuvwxyz    "[rng.Intn(30)]
+		}
+		texts[i] = string(b)
+	}
+	return texts
+}
+
+// TestUnrelatedTextsStaySeparate compares enough unrelated texts that, at
+// a SimHash threshold of 0.7, chance agreement of fingerprint bits links
+// some of them into clusters
+func TestUnrelatedTextsStaySeparate(t *testing.T) {
+	items := textItems(append(randomTexts(400), unrelated...))
+	for _, method := range []Method{MethodMinHash, MethodSimHash} {
+		if clusters := FindClusters(items, Options{Method: method}); len(clusters) > 0 {
+			t.Errorf("%s clustered unrelated texts: %+v", method, clusters[0].ItemIDs)
+		}
+	}
+}
+
+func TestEditsCluster(t *testing.T) {
+	var texts []string
+	for i := 0; i < 4; i++ {
+		texts = append(texts, fmt.Sprintf("rsync -avz --delete ./build/ deploy@example.com:/var/www/site%d", i))
+	}
+	texts = append(texts, unrelated...)
+	items := textItems(texts)
+
+	for _, method := range []Method{MethodMinHash, MethodSimHash} {
+		clusters := FindClusters(items, Options{Method: method})
+		if len(clusters) != 1 || clusters[0].Size != 4 {
+			t.Errorf("%s found %d clusters, want one of the 4 edits: %+v", method, len(clusters), clusters)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+const (
+	// shingleSize is the length in runes of the character n-grams hashed
+	// into signatures
+	shingleSize = 4
+	// minHashSize is the number of hash functions in a MinHash signature
+	minHashSize = 64
+	// minHashBands splits a MinHash signature for locality-sensitive hashing;
+	// minHashSize must be a multiple of it
+	minHashBands = 16
+)
+
+// Method selects the similarity signature
+type Method string
+
+const (
+	// MethodMinHash estimates the Jaccard similarity of character shingles
+	MethodMinHash Method = "minhash"
+	// MethodSimHash compares 64-bit SimHash fingerprints by Hamming distance
+	MethodSimHash Method = "simhash"
+)
+
+// signature is a similarity fingerprint of one text
+type signature interface {
+	// similarity returns a score between 0 and 1
+	similarity(other signature) float64
+}
+
+type minHash [minHashSize]uint64
+
+func (m *minHash) similarity(other signature) float64 {
+	o := other.(*minHash)
+	equal := 0
+	for i := range m {
+		if m[i] == o[i] {
+			equal++
+		}
+	}
+	return float64(equal) / minHashSize
+}
+
+// bands returns one hash per band of the signature; texts sharing any band
+// hash are candidates for comparison
+func (m *minHash) bands() []uint64 {
+	rows := minHashSize / minHashBands
+	bands := make([]uint64, minHashBands)
+	for b := range bands {
+		h := uint64(b) + 1
+		for _, v := range m[b*rows : (b+1)*rows] {
+			h = mix(h ^ v)
+		}
+		bands[b] = h
+	}
+	return bands
+}
+
+type simHash uint64
+
+func (s simHash) similarity(other signature) float64 {
+	distance := bits.OnesCount64(uint64(s ^ other.(simHash)))
+	return 1 - float64(distance)/64
+}
+
+func newMinHash(shingles []uint64) *minHash {
+	var m minHash
+	for i := range m {
+		m[i] = ^uint64(0)
+	}
+	for _, shingle := range shingles {
+		for i := range m {
+			if v := mix(shingle ^ seeds[i]); v < m[i] {
+				m[i] = v
+			}
+		}
+	}
+	return &m
+}
+
+func newSimHash(shingles []uint64) simHash {
+	var weights [64]int
+	for _, shingle := range shingles {
+		for bit := range weights {
+			if shingle&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var s simHash
+	for bit, weight := range weights {
+		if weight > 0 {
+			s |= 1 << bit
+		}
+	}
+	return s
+}
+
+// shingles hashes the distinct character n-grams of text, which is expected
+// to be normalized already
+func shingles(text string) []uint64 {
+	runes := []rune(text)
+	if len(runes) <= shingleSize {
+		return []uint64{hashString(text)}
+	}
+
+	seen := map[uint64]bool{}
+	var result []uint64
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		h := hashString(string(runes[i : i+shingleSize]))
+		if !seen[h] {
+			seen[h] = true
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer, used to derive independent hash
+// functions from one base hash
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// seeds holds one fixed value per MinHash function so signatures are stable
+// across runs
+var seeds = func() [minHashSize]uint64 {
+	var s [minHashSize]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x += 0x9e3779b97f4a7c15
+		s[i] = mix(x)
+	}
+	return s
+}()
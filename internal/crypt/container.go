@@ -0,0 +1,230 @@
+// Package crypt implements the encrypted container used for exports.
+//
+// A container is laid out as:
+//
+//	magic   8 bytes  "SSPARROW"
+//	version 1 byte   currently 1
+//	length  4 bytes  big-endian length of the header
+//	header  JSON     Header, describing the key derivation and inner format
+//	payload          AES-256-GCM ciphertext of the inner export
+//
+// The magic, version, length and header bytes are authenticated as
+// additional data, so tampering with any of them makes decryption fail.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	magic   = "SSPARROW"
+	version = 1
+
+	// KDFArgon2id derives the key from a passphrase
+	KDFArgon2id = "argon2id"
+	// KDFHKDF derives the key from the contents of a key file
+	KDFHKDF = "hkdf-sha256"
+
+	cipherAESGCM = "aes-256-gcm"
+	keyLength    = 32
+	saltLength   = 16
+	hkdfInfo     = "sunlitsparrow export"
+	prefixLength = len(magic) + 1 + 4
+	maxHeader    = 64 * 1024
+
+	// Limits on the Argon2id parameters read from a header, which is only
+	// authenticated after the key is derived, so a forged file cannot make
+	// the derivation allocate or compute without bound
+	maxArgonTime    = 10
+	maxArgonMemory  = 1024 * 1024 // KiB, 1 GiB
+	maxArgonThreads = 16
+)
+
+// ErrNotEncrypted is returned when data does not start with the container magic
+var ErrNotEncrypted = errors.New("data is not an encrypted container")
+
+// ErrDecrypt is returned when the key is wrong or the container was modified
+var ErrDecrypt = errors.New("decryption failed: wrong passphrase or key file, or corrupted file")
+
+// Key is the secret protecting a container: either a passphrase or the
+// contents of a key file
+type Key struct {
+	Passphrase []byte
+	KeyFile    []byte
+}
+
+// Header describes how a container was encrypted
+type Header struct {
+	// Format names the inner export format, e.g. "json"
+	Format string `json:"format"`
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"`
+	Salt   []byte `json:"salt"`
+	Nonce  []byte `json:"nonce"`
+	// Argon2id parameters; unused for key files
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// IsEncrypted reports whether data starts with the container magic
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Seal encrypts plaintext of the given inner format into a container
+func Seal(plaintext []byte, format string, key Key) ([]byte, error) {
+	header := Header{
+		Format: format,
+		Cipher: cipherAESGCM,
+		Salt:   make([]byte, saltLength),
+	}
+	if _, err := rand.Read(header.Salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %w", err)
+	}
+
+	switch {
+	case len(key.KeyFile) > 0:
+		header.KDF = KDFHKDF
+	case len(key.Passphrase) > 0:
+		header.KDF = KDFArgon2id
+		header.Time, header.Memory, header.Threads = 3, 64*1024, 4
+	default:
+		return nil, errors.New("no passphrase or key file given")
+	}
+
+	derived, err := deriveKey(header, key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(header.Nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding header: %w", err)
+	}
+
+	prefix := make([]byte, 0, prefixLength+len(headerJSON))
+	prefix = append(prefix, magic...)
+	prefix = append(prefix, version)
+	prefix = binary.BigEndian.AppendUint32(prefix, uint32(len(headerJSON)))
+	prefix = append(prefix, headerJSON...)
+
+	return aead.Seal(prefix, header.Nonce, plaintext, prefix), nil
+}
+
+// ReadHeader parses the header of a container without decrypting it
+func ReadHeader(data []byte) (*Header, error) {
+	header, _, err := splitContainer(data)
+	return header, err
+}
+
+// Open decrypts a container, returning the plaintext and its header
+func Open(data []byte, key Key) ([]byte, *Header, error) {
+	header, headerEnd, err := splitContainer(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	derived, err := deriveKey(*header, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce length %d", len(header.Nonce))
+	}
+
+	plaintext, err := aead.Open(nil, header.Nonce, data[headerEnd:], data[:headerEnd])
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return plaintext, header, nil
+}
+
+// splitContainer validates the container prefix and returns the parsed
+// header and the offset at which the ciphertext starts
+func splitContainer(data []byte) (*Header, int, error) {
+	if !IsEncrypted(data) {
+		return nil, 0, ErrNotEncrypted
+	}
+	if len(data) < prefixLength {
+		return nil, 0, errors.New("truncated container")
+	}
+	if v := data[len(magic)]; v != version {
+		return nil, 0, fmt.Errorf("unsupported container version %d", v)
+	}
+
+	headerLength := binary.BigEndian.Uint32(data[len(magic)+1 : prefixLength])
+	if headerLength > maxHeader || int(headerLength) > len(data)-prefixLength {
+		return nil, 0, errors.New("invalid container header length")
+	}
+	headerEnd := prefixLength + int(headerLength)
+
+	var header Header
+	if err := json.Unmarshal(data[prefixLength:headerEnd], &header); err != nil {
+		return nil, 0, fmt.Errorf("error parsing container header: %w", err)
+	}
+	if header.Cipher != cipherAESGCM {
+		return nil, 0, fmt.Errorf("unsupported cipher %q", header.Cipher)
+	}
+
+	return &header, headerEnd, nil
+}
+
+// deriveKey turns the user secret into an AES key as described by the header
+func deriveKey(header Header, key Key) ([]byte, error) {
+	if len(header.Salt) != saltLength {
+		return nil, fmt.Errorf("invalid salt length %d", len(header.Salt))
+	}
+
+	switch header.KDF {
+	case KDFArgon2id:
+		if len(key.Passphrase) == 0 {
+			return nil, errors.New("container is protected by a passphrase")
+		}
+		if header.Time == 0 || header.Memory == 0 || header.Threads == 0 ||
+			header.Time > maxArgonTime || header.Memory > maxArgonMemory || header.Threads > maxArgonThreads {
+			return nil, fmt.Errorf("invalid argon2id parameters: time %d, memory %d KiB, threads %d",
+				header.Time, header.Memory, header.Threads)
+		}
+		return argon2.IDKey(key.Passphrase, header.Salt, header.Time, header.Memory, header.Threads, keyLength), nil
+	case KDFHKDF:
+		if len(key.KeyFile) == 0 {
+			return nil, errors.New("container is protected by a key file")
+		}
+		return hkdf.Key(sha256.New, key.KeyFile, header.Salt, hkdfInfo, keyLength)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", header.KDF)
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
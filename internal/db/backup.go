@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+)
+
+// Backup writes a consistent copy of the database, including any pending
+// write-ahead log, into dir (or next to the database when dir is empty) and
+// returns the path of the copy
+func Backup(db *sql.DB, dbPath, dir string) (string, error) {
+	if dir == "" {
+		dir = filepath.Dir(dbPath)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+	backupPath := filepath.Join(dir, fmt.Sprintf("%s.backup-%s.sqlite", base, time.Now().Format("20060102-150405")))
+
+	if _, err := db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return "", fmt.Errorf("error backing up database: %w", err)
+	}
+
+	logger.Info("Backed up database", "file", backupPath)
+	return backupPath, nil
+}
@@ -11,11 +11,11 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// OpenMaccyDB opens a connection to the Maccy database
-func OpenMaccyDB() (*sql.DB, error) {
+// FindMaccyDB returns the path of the Maccy database
+func FindMaccyDB() (string, error) {
 	usr, err := user.Current()
 	if err != nil {
-		return nil, fmt.Errorf("error getting current user: %w", err)
+		return "", fmt.Errorf("error getting current user: %w", err)
 	}
 
 	// Try multiple possible paths for Maccy database
@@ -29,23 +29,30 @@ func OpenMaccyDB() (*sql.DB, error) {
 	possiblePaths = append(possiblePaths, "Maccy-Storage.sqlite")
 
 	// Try each path
-	var foundPath string
 	for _, path := range possiblePaths {
-		logger.Debug("Checking database path: %s", path)
+		logger.Debug("Checking database path", "file", path)
 		if _, err := os.Stat(path); err == nil {
-			foundPath = path
-			logger.Info("Found Maccy database at: %s", foundPath)
-			break
+			logger.Info("Found Maccy database", "file", path)
+			return path, nil
 		}
 	}
 
-	// If no database found, return error
-	if foundPath == "" {
-		logger.Info("No Maccy database found in any expected location")
-		return nil, fmt.Errorf("Maccy database not found in any expected location. You can place a database file named 'Maccy-Storage.sqlite' in the current directory for testing")
+	logger.Info("No Maccy database found in any expected location")
+	return "", fmt.Errorf("Maccy database not found in any expected location. You can place a database file named 'Maccy-Storage.sqlite' in the current directory for testing")
+}
+
+// OpenMaccyDB opens a connection to the Maccy database
+func OpenMaccyDB() (*sql.DB, error) {
+	path, err := FindMaccyDB()
+	if err != nil {
+		return nil, err
 	}
+	return Open(path)
+}
 
-	db, err := sql.Open("sqlite3", foundPath)
+// Open opens a connection to the SQLite database at path
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("error opening SQLite database: %w", err)
 	}
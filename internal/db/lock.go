@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrLocked is returned when another process holds a write lock on the database
+var ErrLocked = errors.New("database is locked by another process (is Maccy writing to it?)")
+
+// CheckWriteLock reports ErrLocked when another connection, typically Maccy
+// itself, currently holds a write lock. It briefly takes and releases the
+// lock without waiting.
+func CheckWriteLock(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA busy_timeout = 0"); err != nil {
+		return fmt.Errorf("error setting busy timeout: %w", err)
+	}
+	// Restore the driver default so the pooled connection keeps waiting on locks
+	defer conn.ExecContext(ctx, "PRAGMA busy_timeout = 5000")
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		if strings.Contains(err.Error(), "locked") || strings.Contains(err.Error(), "busy") {
+			return ErrLocked
+		}
+		return fmt.Errorf("error probing write lock: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "ROLLBACK"); err != nil {
+		return fmt.Errorf("error releasing write lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+	"github.com/mattn/go-sqlite3"
+)
+
+// readOnlyDriver is the driver name of connections that may only read
+const readOnlyDriver = "sqlite3_readonly"
+
+// Action codes passed to the authorizer that mattn/go-sqlite3 does not export
+const (
+	actionFunction  = 31
+	actionRecursive = 33
+)
+
+// inspectionPragmas only read, with or without an argument
+var inspectionPragmas = map[string]bool{
+	"collation_list":   true,
+	"compile_options":  true,
+	"database_list":    true,
+	"foreign_key_list": true,
+	"function_list":    true,
+	"index_info":       true,
+	"index_list":       true,
+	"index_xinfo":      true,
+	"integrity_check":  true,
+	"module_list":      true,
+	"pragma_list":      true,
+	"quick_check":      true,
+	"table_info":       true,
+	"table_list":       true,
+	"table_xinfo":      true,
+}
+
+// settingPragmas read a value without an argument but change it with one
+var settingPragmas = map[string]bool{
+	"application_id": true,
+	"encoding":       true,
+	"freelist_count": true,
+	"page_count":     true,
+	"page_size":      true,
+	"schema_version": true,
+	"user_version":   true,
+}
+
+func init() {
+	sql.Register(readOnlyDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.RegisterAuthorizer(authorizeRead)
+			return nil
+		},
+	})
+}
+
+// authorizeRead allows reading tables, calling functions and inspecting the
+// schema, and denies everything else, including attaching databases and
+// pragmas that change settings
+func authorizeRead(action int, arg1, arg2, _ string) int {
+	switch action {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, actionFunction, actionRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_PRAGMA:
+		if inspectionPragmas[arg1] || (settingPragmas[arg1] && arg2 == "") {
+			return sqlite3.SQLITE_OK
+		}
+	}
+	return sqlite3.SQLITE_DENY
+}
+
+// OpenReadOnly opens the SQLite database at path for reading only. The file
+// is opened read-only with query_only set, and an authorizer rejects any
+// statement that is not a query. Unlike Open it fails instead of creating a
+// missing database.
+func OpenReadOnly(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("error opening SQLite database: %w", err)
+	}
+
+	db, err := sql.Open(readOnlyDriver, readOnlyDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	logger.Info("Opened database read-only", "file", path)
+	return db, nil
+}
+
+// readOnlyDSN builds the URI filename opening path read-only. The path is
+// made absolute and escaped, so ?, # and % in file names are not taken for
+// URI syntax.
+func readOnlyDSN(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	uri := url.URL{
+		Scheme:   "file",
+		Path:     filepath.ToSlash(path),
+		RawQuery: "mode=ro&_query_only=1",
+	}
+	return uri.String()
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadOnlyRejectsWrites runs statements that would change the database
+// or the connection through OpenReadOnly and checks the authorizer or the
+// read-only file mode refuses every one
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.sqlite")
+	setup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setup.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT); INSERT INTO t (v) VALUES ('a'), ('b')"); err != nil {
+		t.Fatal(err)
+	}
+	setup.Close()
+
+	conn, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	vacuumTarget := filepath.Join(dir, "copy.sqlite")
+	for _, test := range []struct {
+		name  string
+		query string
+	}{
+		{"delete", "DELETE FROM t"},
+		{"insert", "INSERT INTO t (v) VALUES ('c')"},
+		{"drop", "DROP TABLE t"},
+		{"attach", "ATTACH DATABASE '" + filepath.Join(dir, "other.sqlite") + "' AS other"},
+		{"query_only off", "PRAGMA query_only=0"},
+		{"cte delete", "WITH x AS (SELECT 1) DELETE FROM t"},
+		{"vacuum into", "VACUUM INTO '" + vacuumTarget + "'"},
+		{"load_extension", "SELECT load_extension('x')"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := conn.Exec(test.query); err == nil {
+				t.Errorf("%s succeeded", test.query)
+			}
+		})
+	}
+
+	if _, err := os.Stat(vacuumTarget); !os.IsNotExist(err) {
+		t.Errorf("VACUUM INTO created %s", vacuumTarget)
+	}
+
+	var count int
+	if err := conn.QueryRow("WITH x AS (SELECT id FROM t) SELECT count(*) FROM x").Scan(&count); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2 rows left", count)
+	}
+	var pageSize int
+	if err := conn.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		t.Errorf("reading page_size: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// Mode selects how items are considered duplicates
+type Mode string
+
+const (
+	// ModeExact groups items whose contents are byte-for-byte identical
+	ModeExact Mode = "exact"
+	// ModeNormalized groups items whose text is equal ignoring case,
+	// whitespace runs and leading or trailing whitespace
+	ModeNormalized Mode = "normalized"
+)
+
+// ParseMode validates a mode name
+func ParseMode(name string) (Mode, error) {
+	switch Mode(strings.ToLower(name)) {
+	case ModeExact:
+		return ModeExact, nil
+	case ModeNormalized:
+		return ModeNormalized, nil
+	}
+	return "", fmt.Errorf("unknown dedupe mode %q (expected exact or normalized)", name)
+}
+
+// Group is a set of duplicate items
+type Group struct {
+	Key           string                `json:"key"`
+	Mode          Mode                  `json:"mode"`
+	Keep          int                   `json:"keep"`
+	TotalCopies   int                   `json:"totalCopies"`
+	FirstCopiedAt time.Time             `json:"firstCopiedAt"`
+	LastCopiedAt  time.Time             `json:"lastCopiedAt"`
+	Items         []history.HistoryItem `json:"-"`
+	ItemIDs       []int                 `json:"itemIds"`
+	// KeptPinned lists pinned items other than Keep. They are never merged,
+	// so their pins survive; copies and times only cover the merged items.
+	KeptPinned   []int    `json:"keptPinned,omitempty"`
+	Applications []string `json:"applications,omitempty"`
+	Preview      string   `json:"preview"`
+}
+
+// Duplicates returns the IDs of the group's items that are merged into the
+// kept one: all but Keep and KeptPinned
+func (g Group) Duplicates() []int {
+	var ids []int
+	for _, id := range g.ItemIDs {
+		if id != g.Keep && !slices.Contains(g.KeptPinned, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FindGroups groups duplicate items, largest groups first. Items without
+// text are compared by exact content in normalized mode.
+func FindGroups(items []history.HistoryItem, mode Mode) []Group {
+	buckets := map[string][]history.HistoryItem{}
+	var order []string
+
+	for _, item := range items {
+		key := itemKey(item, mode)
+		if key == "" {
+			continue
+		}
+		if _, seen := buckets[key]; !seen {
+			order = append(order, key)
+		}
+		buckets[key] = append(buckets[key], item)
+	}
+
+	var groups []Group
+	for _, key := range order {
+		members := buckets[key]
+		if len(members) < 2 {
+			continue
+		}
+		groups = append(groups, newGroup(key, mode, members))
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Items) != len(groups[j].Items) {
+			return len(groups[i].Items) > len(groups[j].Items)
+		}
+		return groups[i].TotalCopies > groups[j].TotalCopies
+	})
+	return groups
+}
+
+// Merges converts groups into the repository's merge instructions, skipping
+// groups with nothing to merge
+func Merges(groups []Group) []history.Merge {
+	var merges []history.Merge
+	for _, group := range groups {
+		if len(group.Duplicates()) == 0 {
+			continue
+		}
+		merges = append(merges, history.Merge{
+			Keep:          group.Keep,
+			Duplicates:    group.Duplicates(),
+			Copies:        group.TotalCopies,
+			FirstCopiedAt: group.FirstCopiedAt,
+			LastCopiedAt:  group.LastCopiedAt,
+		})
+	}
+	return merges
+}
+
+// Collapse merges every group into its kept item and drops the duplicates,
+// returning the remaining items in their original order
+func Collapse(items []history.HistoryItem, groups []Group) []history.HistoryItem {
+	merged := map[int]history.HistoryItem{}
+	dropped := map[int]bool{}
+
+	for _, group := range groups {
+		for _, id := range group.Duplicates() {
+			dropped[id] = true
+		}
+		for _, item := range group.Items {
+			if item.ID != group.Keep {
+				continue
+			}
+			item.NumberOfCopies = group.TotalCopies
+			item.FirstCopiedAt = group.FirstCopiedAt
+			item.LastCopiedAt = group.LastCopiedAt
+			merged[item.ID] = item
+		}
+	}
+
+	var result []history.HistoryItem
+	for _, item := range items {
+		if dropped[item.ID] {
+			continue
+		}
+		if m, ok := merged[item.ID]; ok {
+			item = m
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func newGroup(key string, mode Mode, members []history.HistoryItem) Group {
+	group := Group{
+		Key:   key[:12],
+		Mode:  mode,
+		Items: members,
+	}
+
+	apps := map[string]bool{}
+	keep := members[0]
+	for _, item := range members {
+		group.ItemIDs = append(group.ItemIDs, item.ID)
+		if item.Application != "" && !apps[item.Application] {
+			apps[item.Application] = true
+			group.Applications = append(group.Applications, item.Application)
+		}
+		if preferKeep(item, keep) {
+			keep = item
+		}
+	}
+
+	for _, item := range members {
+		if item.ID != keep.ID && item.Pin != "" {
+			group.KeptPinned = append(group.KeptPinned, item.ID)
+			continue
+		}
+		group.TotalCopies += item.NumberOfCopies
+		if group.FirstCopiedAt.IsZero() || (!item.FirstCopiedAt.IsZero() && item.FirstCopiedAt.Before(group.FirstCopiedAt)) {
+			group.FirstCopiedAt = item.FirstCopiedAt
+		}
+		if item.LastCopiedAt.After(group.LastCopiedAt) {
+			group.LastCopiedAt = item.LastCopiedAt
+		}
+	}
+
+	group.Keep = keep.ID
+	group.Preview = strings.Join(strings.Fields(keep.Title), " ")
+	return group
+}
+
+// preferKeep reports whether candidate should survive instead of current:
+// pinned items win, then the most recently copied one
+func preferKeep(candidate, current history.HistoryItem) bool {
+	if (candidate.Pin != "") != (current.Pin != "") {
+		return candidate.Pin != ""
+	}
+	return candidate.LastCopiedAt.After(current.LastCopiedAt)
+}
+
+// itemKey returns the grouping key of an item, or an empty string if the
+// item has no contents to compare
+func itemKey(item history.HistoryItem, mode Mode) string {
+	if mode == ModeNormalized {
+		if text := NormalizeText(item.DecodedText()); text != "" {
+			sum := sha256.Sum256([]byte(text))
+			return hex.EncodeToString(sum[:])
+		}
+	}
+	return ContentHash(item)
+}
+
+// ContentHash hashes all contents of an item independent of their order
+func ContentHash(item history.HistoryItem) string {
+	if len(item.Contents) == 0 {
+		return ""
+	}
+
+	contents := append([]history.Content(nil), item.Contents...)
+	sort.Slice(contents, func(i, j int) bool {
+		return contents[i].Type < contents[j].Type
+	})
+
+	h := sha256.New()
+	for _, content := range contents {
+		fmt.Fprintf(h, "%s\x00%d\x00", content.Type, len(content.Value))
+		h.Write(content.Value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NormalizeText lowercases text and collapses all whitespace
+func NormalizeText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -1,16 +1,38 @@
 package export
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/gkwa/sunlitsparrow/internal/crypt"
 	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/redact"
 )
 
 // JSONExporter handles exporting of history items to JSON
 type JSONExporter struct {
 	outputFile string
+	redactions []redact.Record
+	redacted   bool
+	key        *crypt.Key
+}
+
+// Document is the export layout used when metadata has to be recorded
+// alongside the items. Plain exports are a bare JSON array of items.
+type Document struct {
+	Metadata Metadata              `json:"metadata"`
+	Items    []history.HistoryItem `json:"items"`
+}
+
+// Metadata describes how an export was produced
+type Metadata struct {
+	ExportedAt time.Time       `json:"exportedAt"`
+	ItemCount  int             `json:"itemCount"`
+	Redacted   bool            `json:"redacted"`
+	Redactions []redact.Record `json:"redactions,omitempty"`
 }
 
 // NewJSONExporter creates a new JSON exporter
@@ -18,19 +40,55 @@ func NewJSONExporter(outputFile string) *JSONExporter {
 	return &JSONExporter{outputFile: outputFile}
 }
 
+// SetRedactions marks the export as redacted and records which items were
+// changed, switching the output to the Document layout
+func (e *JSONExporter) SetRedactions(records []redact.Record) {
+	e.redacted = true
+	e.redactions = records
+}
+
+// SetEncryption makes the exporter wrap its output in an encrypted container
+func (e *JSONExporter) SetEncryption(key crypt.Key) {
+	e.key = &key
+}
+
 // Export exports history items to a JSON file
 func (e *JSONExporter) Export(items []history.HistoryItem) error {
-	file, err := os.Create(e.outputFile)
-	if err != nil {
-		return fmt.Errorf("error creating output file: %w", err)
+
+	var payload interface{} = items
+	if e.redacted {
+		payload = Document{
+			Metadata: Metadata{
+				ExportedAt: time.Now(),
+				ItemCount:  len(items),
+				Redacted:   true,
+				Redactions: e.redactions,
+			},
+			Items: items,
+		}
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(items); err != nil {
+	if err := encoder.Encode(payload); err != nil {
 		return fmt.Errorf("error encoding JSON: %w", err)
 	}
 
+	data := buf.Bytes()
+	perm := os.FileMode(0o644)
+	if e.key != nil {
+		sealed, err := crypt.Seal(data, FormatJSON, *e.key)
+		if err != nil {
+			return fmt.Errorf("error encrypting export: %w", err)
+		}
+		data = sealed
+		perm = 0o600
+	}
+
+	if err := os.WriteFile(e.outputFile, data, perm); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+
 	return nil
 }
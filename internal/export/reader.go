@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gkwa/sunlitsparrow/internal/crypt"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+)
+
+// FormatJSON names the JSON export format in encrypted container headers
+const FormatJSON = "json"
+
+// KeyFunc supplies the decryption key. It is only called for encrypted files,
+// so interactive prompts are skipped for plaintext exports.
+type KeyFunc func() (crypt.Key, error)
+
+// ReadFile loads an export file, decrypting it first if it is an encrypted
+// container. Both the bare item array and the Document layout are accepted.
+func ReadFile(path string, keyFunc KeyFunc) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export file: %w", err)
+	}
+
+	if crypt.IsEncrypted(data) {
+		if keyFunc == nil {
+			return nil, fmt.Errorf("%s is encrypted but no key was provided", path)
+		}
+		key, err := keyFunc()
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, header, err := crypt.Open(data, key)
+		if err != nil {
+			return nil, err
+		}
+		if header.Format != FormatJSON {
+			return nil, fmt.Errorf("unsupported export format %q", header.Format)
+		}
+		logger.Debug("Decrypted export", "file", path, "format", header.Format, "kdf", header.KDF)
+		data = plaintext
+	}
+
+	return parseDocument(data)
+}
+
+// parseDocument decodes either export layout into a Document
+func parseDocument(data []byte) (*Document, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var items []history.HistoryItem
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("error parsing export: %w", err)
+		}
+		return &Document{Metadata: Metadata{ItemCount: len(items)}, Items: items}, nil
+	}
+
+	var doc Document
+	if err := json.Unmarshal(trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing export: %w", err)
+	}
+	return &doc, nil
+}
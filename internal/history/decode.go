@@ -0,0 +1,139 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	_ "image/png"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	htmlSkipPattern    = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+	rtfControlPattern  = regexp.MustCompile(`\\([a-z]+)(-?\d+)? ?|\\'([0-9a-f]{2})|\\([{}\\])|[{}]`)
+	rtfIgnoredGroupKey = regexp.MustCompile(`^\{\\(\*|fonttbl|colortbl|stylesheet|info|expandedcolortbl)`)
+)
+
+// DecodedText returns a human-readable rendering of the content: text as is,
+// HTML and RTF with markup stripped, and a short summary for binary data
+func (c Content) DecodedText() string {
+	switch c.Type {
+	case ContentTypeText, ContentTypeFileURL:
+		return string(c.Value)
+	case ContentTypeHTML:
+		return htmlToText(string(c.Value))
+	case ContentTypeRTF:
+		return rtfToText(string(c.Value))
+	}
+
+	if config, format, err := image.DecodeConfig(bytes.NewReader(c.Value)); err == nil {
+		return fmt.Sprintf("[%s image, %dx%d, %d bytes]", strings.ToUpper(format), config.Width, config.Height, len(c.Value))
+	}
+	if c.Type == ContentTypeTIFF {
+		return fmt.Sprintf("[TIFF image, %d bytes]", len(c.Value))
+	}
+	if utf8.Valid(c.Value) {
+		return string(c.Value)
+	}
+	return fmt.Sprintf("[%s, %d bytes]", c.Type, len(c.Value))
+}
+
+// IsText reports whether the content decodes to meaningful text: one of
+// the known text types, or any other type holding valid UTF-8 without NUL
+// bytes. Images and other binary data are not text.
+func (c Content) IsText() bool {
+	switch c.Type {
+	case ContentTypeText, ContentTypeFileURL, ContentTypeHTML, ContentTypeRTF:
+		return true
+	}
+	return len(c.Value) > 0 && utf8.Valid(c.Value) && bytes.IndexByte(c.Value, 0) < 0
+}
+
+// DecodedText returns the best textual rendering of the item: the plain text
+// content if present, otherwise the first decodable rich text content
+func (h HistoryItem) DecodedText() string {
+	if text := h.Text(); text != "" {
+		return text
+	}
+	for _, content := range h.Contents {
+		if content.IsText() {
+			return content.DecodedText()
+		}
+	}
+	return ""
+}
+
+// htmlToText strips tags from an HTML fragment, keeping line structure
+func htmlToText(s string) string {
+	s = htmlSkipPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankLinesPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// rtfToText extracts the visible text of an RTF document. It understands
+// enough of the format for pasteboard snippets, not arbitrary documents.
+func rtfToText(s string) string {
+	s = stripRTFGroups(s)
+
+	var out strings.Builder
+	last := 0
+	for _, m := range rtfControlPattern.FindAllStringSubmatchIndex(s, -1) {
+		out.WriteString(strings.NewReplacer("\r", "", "\n", "").Replace(s[last:m[0]]))
+		last = m[1]
+
+		switch {
+		case m[2] >= 0:
+			switch s[m[2]:m[3]] {
+			case "par", "line":
+				out.WriteString("\n")
+			case "tab":
+				out.WriteString("\t")
+			}
+		case m[6] >= 0:
+			var b byte
+			fmt.Sscanf(s[m[6]:m[7]], "%02x", &b)
+			out.WriteRune(rune(b))
+		case m[8] >= 0:
+			out.WriteString(s[m[8]:m[9]])
+		}
+	}
+	out.WriteString(s[last:])
+
+	return strings.TrimSpace(out.String())
+}
+
+// stripRTFGroups removes destination groups such as font and color tables
+func stripRTFGroups(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '{' && rtfIgnoredGroupKey.MatchString(s[i:]) {
+			depth := 0
+			for ; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+					continue
+				}
+				if s[i] == '{' {
+					depth++
+				} else if s[i] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,867 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expr is a type-checked filter expression over history items, such as
+//
+//	app == "com.apple.Safari" && copies > 3 && text =~ /https?:/ && age < 7d
+//
+// Each comparison has a field on one side and a literal on the other.
+// Conditions that can be expressed in SQL are applied by the repository's
+// query; the rest, such as regular expressions and the decoded text, are
+// evaluated in Go.
+type Expr struct {
+	source string
+	root   node
+	// now is the reference time for age comparisons, fixed when parsing so
+	// the SQL and Go halves of a filter agree
+	now time.Time
+}
+
+// valueType is the type of a field or literal
+type valueType int
+
+const (
+	typeString valueType = iota
+	typeNumber
+	typeTime
+	typeDuration
+	typeBool
+	typeRegex
+)
+
+func (t valueType) String() string {
+	return [...]string{"string", "number", "time", "duration", "bool", "regex"}[t]
+}
+
+// field describes an item attribute usable in expressions
+type field struct {
+	name string
+	typ  valueType
+	// multi is set for fields with several values per item, which match if
+	// any value does (and for != and !~, if none does)
+	multi bool
+	// sql returns the column expression for a schema flavor, or an empty
+	// string if the field can only be evaluated in Go
+	sql func(c itemColumns) string
+	// value returns the values of the field for an item
+	value func(item HistoryItem, now time.Time) []interface{}
+}
+
+var fields = map[string]*field{
+	"id": {name: "id", typ: typeNumber,
+		sql:   func(c itemColumns) string { return c.id },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{float64(item.ID)} }},
+	"title": {name: "title", typ: typeString,
+		sql:   func(c itemColumns) string { return "COALESCE(" + c.title + ", '')" },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.Title} }},
+	"text": {name: "text", typ: typeString,
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.DecodedText()} }},
+	"app": {name: "app", typ: typeString,
+		sql:   func(c itemColumns) string { return "COALESCE(" + c.application + ", '')" },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.Application} }},
+	"pin": {name: "pin", typ: typeString,
+		sql:   func(c itemColumns) string { return "COALESCE(" + c.pin + ", '')" },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.Pin} }},
+	"pinned": {name: "pinned", typ: typeBool,
+		sql:   func(c itemColumns) string { return "(COALESCE(" + c.pin + ", '') != '')" },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.Pin != ""} }},
+	"copies": {name: "copies", typ: typeNumber,
+		sql:   func(c itemColumns) string { return c.numberOfCopies },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{float64(item.NumberOfCopies)} }},
+	"first": {name: "first", typ: typeTime,
+		sql:   func(c itemColumns) string { return c.firstCopiedAt },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.FirstCopiedAt} }},
+	"last": {name: "last", typ: typeTime,
+		sql:   func(c itemColumns) string { return c.lastCopiedAt },
+		value: func(item HistoryItem, _ time.Time) []interface{} { return []interface{}{item.LastCopiedAt} }},
+	"age": {name: "age", typ: typeDuration,
+		sql:   func(c itemColumns) string { return c.lastCopiedAt },
+		value: func(item HistoryItem, now time.Time) []interface{} { return []interface{}{now.Sub(item.LastCopiedAt)} }},
+	"type": {name: "type", typ: typeString, multi: true,
+		value: func(item HistoryItem, _ time.Time) []interface{} {
+			types := make([]interface{}, len(item.Contents))
+			for i, content := range item.Contents {
+				types[i] = content.Type
+			}
+			return types
+		}},
+}
+
+// fieldAliases are alternative names of fields
+var fieldAliases = map[string]string{
+	"application":    "app",
+	"firstCopiedAt":  "first",
+	"lastCopiedAt":   "last",
+	"numberOfCopies": "copies",
+}
+
+// ExprFields lists the field names usable in expressions
+func ExprFields() []string {
+	return []string{"id", "title", "text", "app", "pin", "pinned", "copies", "first", "last", "age", "type"}
+}
+
+// ParseExpr parses and type-checks a filter expression
+func ParseExpr(source string) (*Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+
+	return &Expr{source: source, root: root, now: time.Now()}, nil
+}
+
+// String returns the source of the expression
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Match reports whether an item satisfies the expression
+func (e *Expr) Match(item HistoryItem) bool {
+	return e.root.eval(item, e.now)
+}
+
+// conjuncts splits the expression at its top-level && operators
+func (e *Expr) conjuncts() []node {
+	var conjuncts []node
+	var flatten func(n node)
+	flatten = func(n node) {
+		if and, ok := n.(andNode); ok {
+			flatten(and.left)
+			flatten(and.right)
+			return
+		}
+		conjuncts = append(conjuncts, n)
+	}
+	flatten(e.root)
+	return conjuncts
+}
+
+// UsesContents reports whether evaluating the expression in Go needs item
+// contents, that is whether a condition that cannot run in SQL refers to
+// the text or types of an item
+func (e *Expr) UsesContents() bool {
+	for _, n := range e.conjuncts() {
+		if _, _, ok := n.sql(standardColumns, e.now); !ok && referencesContents(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesContents reports whether a node compares the text or types
+func referencesContents(n node) bool {
+	switch n := n.(type) {
+	case andNode:
+		return referencesContents(n.left) || referencesContents(n.right)
+	case orNode:
+		return referencesContents(n.left) || referencesContents(n.right)
+	case notNode:
+		return referencesContents(n.operand)
+	case compareNode:
+		return n.field.name == "text" || n.field.name == "type"
+	}
+	return false
+}
+
+// node is an element of the expression tree
+type node interface {
+	eval(item HistoryItem, now time.Time) bool
+	// sql returns a SQL condition and its arguments, or ok = false if the
+	// node cannot be expressed in SQL
+	sql(c itemColumns, now time.Time) (cond string, args []interface{}, ok bool)
+}
+
+type andNode struct{ left, right node }
+
+type orNode struct{ left, right node }
+
+type notNode struct{ operand node }
+
+// compareNode compares a field with one literal or, for "in", several
+type compareNode struct {
+	field  *field
+	op     string
+	values []interface{}
+}
+
+func (n andNode) eval(item HistoryItem, now time.Time) bool {
+	return n.left.eval(item, now) && n.right.eval(item, now)
+}
+
+func (n orNode) eval(item HistoryItem, now time.Time) bool {
+	return n.left.eval(item, now) || n.right.eval(item, now)
+}
+
+func (n notNode) eval(item HistoryItem, now time.Time) bool {
+	return !n.operand.eval(item, now)
+}
+
+func (n compareNode) eval(item HistoryItem, now time.Time) bool {
+	values := n.field.value(item, now)
+	negated := n.op == "!=" || n.op == "!~"
+
+	op := n.op
+	if n.field.multi && negated {
+		// A multi-valued field differs when no value matches
+		op = map[string]string{"!=": "==", "!~": "=~"}[op]
+	}
+
+	any := false
+	for _, v := range values {
+		if compareValue(v, op, n.values) {
+			any = true
+			break
+		}
+	}
+	if n.field.multi && negated {
+		return !any
+	}
+	return any
+}
+
+// compareValue applies a comparison to one field value
+func compareValue(v interface{}, op string, literals []interface{}) bool {
+	switch op {
+	case "in":
+		for _, literal := range literals {
+			if compareValue(v, "==", []interface{}{literal}) {
+				return true
+			}
+		}
+		return false
+	case "=~":
+		return literals[0].(*regexp.Regexp).MatchString(v.(string))
+	case "!~":
+		return !literals[0].(*regexp.Regexp).MatchString(v.(string))
+	}
+
+	var c int
+	switch v := v.(type) {
+	case string:
+		c = strings.Compare(v, literals[0].(string))
+	case float64:
+		c = compareFloat(v, literals[0].(float64))
+	case time.Time:
+		c = v.Compare(literals[0].(time.Time))
+	case time.Duration:
+		c = compareFloat(float64(v), float64(literals[0].(time.Duration)))
+	case bool:
+		if v == literals[0].(bool) {
+			c = 0
+		} else {
+			c = 1
+		}
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (n andNode) sql(c itemColumns, now time.Time) (string, []interface{}, bool) {
+	return joinSQL(c, now, "AND", n.left, n.right)
+}
+
+func (n orNode) sql(c itemColumns, now time.Time) (string, []interface{}, bool) {
+	return joinSQL(c, now, "OR", n.left, n.right)
+}
+
+func joinSQL(c itemColumns, now time.Time, op string, left, right node) (string, []interface{}, bool) {
+	l, largs, ok := left.sql(c, now)
+	if !ok {
+		return "", nil, false
+	}
+	r, rargs, ok := right.sql(c, now)
+	if !ok {
+		return "", nil, false
+	}
+	return fmt.Sprintf("(%s %s %s)", l, op, r), append(largs, rargs...), true
+}
+
+func (n notNode) sql(c itemColumns, now time.Time) (string, []interface{}, bool) {
+	cond, args, ok := n.operand.sql(c, now)
+	if !ok {
+		return "", nil, false
+	}
+	return "NOT " + cond, args, true
+}
+
+func (n compareNode) sql(c itemColumns, now time.Time) (string, []interface{}, bool) {
+	if n.op == "=~" || n.op == "!~" {
+		return "", nil, false
+	}
+
+	if n.field.name == "type" {
+		exists := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s = %s.%s AND %s",
+			c.contentTable, c.contentItem, c.table, c.id, c.contentType)
+		switch n.op {
+		case "==":
+			return exists + " = ?)", n.values, true
+		case "!=":
+			return "NOT " + exists + " = ?)", n.values, true
+		case "in":
+			return exists + " IN (" + placeholders(len(n.values)) + "))", n.values, true
+		}
+		return "", nil, false
+	}
+
+	if n.field.sql == nil {
+		return "", nil, false
+	}
+	column := n.field.sql(c)
+
+	args := make([]interface{}, len(n.values))
+	for i, v := range n.values {
+		args[i] = sqlValue(v, now)
+	}
+
+	op := n.op
+	if n.field.typ == typeDuration {
+		// age < d means last copied after now - d
+		op = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}[op]
+	}
+
+	switch op {
+	case "in":
+		return fmt.Sprintf("%s IN (%s)", column, placeholders(len(args))), args, true
+	case "==":
+		return column + " = ?", args, true
+	}
+	return fmt.Sprintf("%s %s ?", column, op), args, true
+}
+
+// sqlValue converts a literal to a SQL argument; times and durations become
+// Cocoa timestamps
+func sqlValue(v interface{}, now time.Time) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return timeToCocoaTimestamp(v)
+	case time.Duration:
+		return timeToCocoaTimestamp(now.Add(-v))
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// conditions splits the expression into its top-level conjuncts and returns
+// the SQL for those that can be compiled, and whether any could not
+func (e *Expr) conditions(c itemColumns) (conds []string, args []interface{}, residual bool) {
+	for _, n := range e.conjuncts() {
+		cond, condArgs, ok := n.sql(c, e.now)
+		if !ok {
+			residual = true
+			continue
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	return conds, args, residual
+}
+
+// operators allowed for each type
+var typeOperators = map[valueType][]string{
+	typeString:   {"==", "!=", "<", "<=", ">", ">=", "=~", "!~", "in"},
+	typeNumber:   {"==", "!=", "<", "<=", ">", ">=", "in"},
+	typeTime:     {"==", "!=", "<", "<=", ">", ">="},
+	typeDuration: {"<", "<=", ">", ">="},
+	typeBool:     {"==", "!="},
+}
+
+// tokenKind classifies lexer tokens
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenTime
+	tokenRegex
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString, tokenRegex:
+		return t.text
+	}
+	return strconv.Quote(t.text)
+}
+
+var (
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2})?)?`)
+	durationPattern = regexp.MustCompile(`^(\d+(\.\d+)?(ms|s|m|h|d|w))+`)
+	numberPattern   = regexp.MustCompile(`^-?\d+(\.\d+)?`)
+	durationPart    = regexp.MustCompile(`(\d+(?:\.\d+)?)(ms|s|m|h|d|w)`)
+)
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// operatorTokens are matched longest first
+var operatorTokens = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(source) {
+		c := rune(source[i])
+		if unicode.IsSpace(c) {
+			i++
+			continue
+		}
+		rest := source[i:]
+
+		switch {
+		case c == '"' || c == '\'':
+			text, value, err := lexString(rest)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %w", i+1, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, value: value, pos: i})
+			i += len(text)
+
+		case c == '/':
+			text, re, err := lexRegex(rest)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %w", i+1, err)
+			}
+			tokens = append(tokens, token{kind: tokenRegex, text: text, value: re, pos: i})
+			i += len(text)
+
+		case datePattern.MatchString(rest):
+			text := datePattern.FindString(rest)
+			t, err := parseDateLiteral(text)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %w", i+1, err)
+			}
+			tokens = append(tokens, token{kind: tokenTime, text: text, value: t, pos: i})
+			i += len(text)
+
+		case durationPattern.MatchString(rest) && !isIdentRune(nextRune(rest, len(durationPattern.FindString(rest)))):
+			text := durationPattern.FindString(rest)
+			tokens = append(tokens, token{kind: tokenDuration, text: text, value: parseDurationLiteral(text), pos: i})
+			i += len(text)
+
+		case numberPattern.MatchString(rest):
+			text := numberPattern.FindString(rest)
+			if isIdentRune(nextRune(rest, len(text))) {
+				return nil, fmt.Errorf("column %d: invalid number or duration %q", i+1, rest[:len(text)+1])
+			}
+			value, _ := strconv.ParseFloat(text, 64)
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: i})
+			i += len(text)
+
+		case isIdentRune(c) && !unicode.IsDigit(c):
+			j := i
+			for j < len(source) && isIdentRune(rune(source[j])) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:j], pos: i})
+			i = j
+
+		default:
+			matched := false
+			for _, op := range operatorTokens {
+				if strings.HasPrefix(rest, op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("column %d: unexpected character %q", i+1, c)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c))
+}
+
+func nextRune(s string, i int) rune {
+	if i >= len(s) {
+		return 0
+	}
+	return rune(s[i])
+}
+
+// lexString reads a quoted string with backslash escapes
+func lexString(s string) (string, string, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case quote:
+			return s[:i+1], b.String(), nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// lexRegex reads /pattern/flags, where flags are i, m and s
+func lexRegex(s string) (string, *regexp.Regexp, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			pattern := strings.ReplaceAll(s[1:i], `\/`, "/")
+			j := i + 1
+			for j < len(s) && strings.ContainsRune("ims", rune(s[j])) {
+				j++
+			}
+			if flags := s[i+1 : j]; flags != "" {
+				pattern = "(?" + flags + ")" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid regular expression: %w", err)
+			}
+			return s[:j], re, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unterminated regular expression")
+}
+
+func parseDateLiteral(text string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+func parseDurationLiteral(text string) time.Duration {
+	var d time.Duration
+	for _, m := range durationPart.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		d += time.Duration(n * float64(durationUnits[m[2]]))
+	}
+	return d
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// isKeyword reports whether tok is an operator or a case-insensitive word
+func isKeyword(tok token, op, word string) bool {
+	return (tok.kind == tokenOperator && tok.text == op) || (tok.kind == tokenIdent && strings.EqualFold(tok.text, word))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "||", "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "&&", "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if isKeyword(p.peek(), "!", "not") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenOperator || tok.text != ")" {
+			return nil, p.errorf(tok, "expected \")\", got %s", tok)
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+var flippedOperators = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+// parseComparison parses "field op literal", "literal op field",
+// "field in [literals]" or a bare boolean field
+func (p *parser) parseComparison() (node, error) {
+	first := p.next()
+
+	if first.kind != tokenIdent {
+		// A literal on the left side: flip the comparison
+		op := p.next()
+		flipped, ok := flippedOperators[op.text]
+		if op.kind != tokenOperator || !ok {
+			return nil, p.errorf(first, "expected a field name, got %s", first)
+		}
+		f, err := p.field(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return p.compare(f, op, flipped, []token{first})
+	}
+
+	f, err := p.field(first)
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if isKeyword(op, "", "in") {
+		p.next()
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return p.compare(f, op, "in", values)
+	}
+	if op.kind != tokenOperator || !isComparison(op.text) {
+		if f.typ == typeBool {
+			return compareNode{field: f, op: "==", values: []interface{}{true}}, nil
+		}
+		return nil, p.errorf(op, "expected a comparison after %s, got %s", f.name, op)
+	}
+	p.next()
+
+	return p.compare(f, op, op.text, []token{p.next()})
+}
+
+func isComparison(op string) bool {
+	_, ok := flippedOperators[op]
+	return ok || op == "=~" || op == "!~"
+}
+
+// field resolves a field name, also accepting true and false as boolean
+// literals on their own
+func (p *parser) field(tok token) (*field, error) {
+	if tok.kind != tokenIdent {
+		return nil, p.errorf(tok, "expected a field name, got %s", tok)
+	}
+	name := tok.text
+	if alias, ok := fieldAliases[name]; ok {
+		name = alias
+	}
+	f, ok := fields[strings.ToLower(name)]
+	if !ok {
+		return nil, p.errorf(tok, "unknown field %q (expected one of %s)", tok.text, strings.Join(ExprFields(), ", "))
+	}
+	return f, nil
+}
+
+// list parses "[literal, ...]"
+func (p *parser) list() ([]token, error) {
+	if tok := p.next(); tok.kind != tokenOperator || tok.text != "[" {
+		return nil, p.errorf(tok, "expected \"[\" after in, got %s", tok)
+	}
+
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == "]" {
+		return nil, p.errorf(tok, "empty list after in; give at least one value")
+	}
+
+	var values []token
+	for {
+		values = append(values, p.next())
+		tok := p.next()
+		if tok.kind == tokenOperator && tok.text == "]" {
+			return values, nil
+		}
+		if tok.kind != tokenOperator || tok.text != "," {
+			return nil, p.errorf(tok, "expected \",\" or \"]\", got %s", tok)
+		}
+	}
+}
+
+// compare type-checks a comparison of a field with literals
+func (p *parser) compare(f *field, opToken token, op string, literals []token) (node, error) {
+	allowed := typeOperators[f.typ]
+	if !containsString(allowed, op) {
+		hint := ""
+		if f.typ == typeDuration && (op == "==" || op == "!=") {
+			// An age is never exactly equal to a duration to the second
+			hint = "; use < or > to compare with a duration"
+		}
+		return nil, p.errorf(opToken, "operator %s cannot be used with %s (%s)%s", op, f.name, f.typ, hint)
+	}
+
+	values := make([]interface{}, len(literals))
+	for i, literal := range literals {
+		typ, value, err := p.literal(literal)
+		if err != nil {
+			return nil, err
+		}
+
+		want := f.typ
+		if op == "=~" || op == "!~" {
+			want = typeRegex
+		}
+		if typ != want {
+			hint := ""
+			if f.typ == typeTime && typ == typeDuration {
+				hint = "; use age to compare with a duration"
+			}
+			return nil, p.errorf(literal, "cannot compare %s (%s) with %s (%s)%s", f.name, f.typ, literal, typ, hint)
+		}
+		values[i] = value
+	}
+
+	return compareNode{field: f, op: op, values: values}, nil
+}
+
+func (p *parser) literal(tok token) (valueType, interface{}, error) {
+	switch tok.kind {
+	case tokenString:
+		return typeString, tok.value, nil
+	case tokenNumber:
+		return typeNumber, tok.value, nil
+	case tokenDuration:
+		return typeDuration, tok.value, nil
+	case tokenTime:
+		return typeTime, tok.value, nil
+	case tokenRegex:
+		return typeRegex, tok.value, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return typeBool, true, nil
+		case "false":
+			return typeBool, false, nil
+		}
+	}
+	return 0, nil, p.errorf(tok, "expected a value, got %s", tok)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{`copies > 3`, []string{"ident copies", "op >", "number 3"}},
+		{`app=="x"&&!pinned`, []string{"ident app", "op ==", `string "x"`, "op &&", "op !", "ident pinned"}},
+		{`title =~ /a\/b/i`, []string{"ident title", "op =~", `regex /a\/b/i`}},
+		{`age <= 1h30m`, []string{"ident age", "op <=", "duration 1h30m"}},
+		{`first >= 2024-01-31T09:30`, []string{"ident first", "op >=", "time 2024-01-31T09:30"}},
+		{`id in [1, -2.5]`, []string{"ident id", "ident in", "op [", "number 1", "op ,", "number -2.5", "op ]"}},
+		{`title == 'it\'s'`, []string{"ident title", "op ==", `string 'it\'s'`}},
+	}
+
+	kinds := map[tokenKind]string{
+		tokenIdent: "ident", tokenString: "string", tokenNumber: "number", tokenDuration: "duration",
+		tokenTime: "time", tokenRegex: "regex", tokenOperator: "op",
+	}
+	for _, tt := range tests {
+		tokens, err := lex(tt.source)
+		if err != nil {
+			t.Errorf("lex(%q): %v", tt.source, err)
+			continue
+		}
+		var got []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			got = append(got, kinds[tok.kind]+" "+tok.text)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("lex(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	for _, source := range []string{`title == "open`, `title =~ /open`, `title =~ /(/`, `copies > 3x`, `copies # 3`} {
+		if _, err := lex(source); err == nil {
+			t.Errorf("lex(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+// describe renders a node as an s-expression to check the tree shape
+func describe(n node) string {
+	switch n := n.(type) {
+	case andNode:
+		return "(and " + describe(n.left) + " " + describe(n.right) + ")"
+	case orNode:
+		return "(or " + describe(n.left) + " " + describe(n.right) + ")"
+	case notNode:
+		return "(not " + describe(n.operand) + ")"
+	case compareNode:
+		values := make([]string, len(n.values))
+		for i, v := range n.values {
+			values[i] = fmt.Sprint(v)
+		}
+		return fmt.Sprintf("[%s %s %s]", n.field.name, n.op, strings.Join(values, ","))
+	}
+	return "?"
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`copies > 1 || copies < 1 && pinned`, "(or [copies > 1] (and [copies < 1] [pinned == true]))"},
+		{`(copies > 1 || copies < 1) && pinned`, "(and (or [copies > 1] [copies < 1]) [pinned == true])"},
+		{`not pinned and app == "a"`, "(and (not [pinned == true]) [app == a])"},
+		{`!(pinned or copies == 2)`, "(not (or [pinned == true] [copies == 2]))"},
+		{`3 < copies`, "[copies > 3]"},
+		{`2d >= age`, "[age <= 48h0m0s]"},
+		{`application in ["a", "b"]`, "[app in a,b]"},
+		{`copies > 1 AND copies < 5 OR pinned`, "(or (and [copies > 1] [copies < 5]) [pinned == true])"},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.source)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.source, err)
+			continue
+		}
+		if got := describe(expr.root); got != tt.want {
+			t.Errorf("ParseExpr(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`last < 7d`, `column 8: cannot compare last (time) with "7d" (duration); use age`},
+		{`copies == "3"`, `column 11: cannot compare copies (number) with "3" (string)`},
+		{`pinned > true`, "column 8: operator > cannot be used with pinned (bool)"},
+		{`copies =~ /3/`, "column 8: operator =~ cannot be used with copies (number)"},
+		{`title =~ "x"`, `column 10: cannot compare title (string) with "x" (string)`},
+		{`first in [2024-01-01]`, "operator in cannot be used with first (time)"},
+		{`size > 3`, `column 1: unknown field "size"`},
+		{`copies >`, "column 9: expected a value, got end of expression"},
+		{`(copies > 1`, `column 12: expected ")", got end of expression`},
+		{`copies > 1 pinned`, `column 12: unexpected "pinned"`},
+		{`app in ["a" "b"]`, `expected "," or "]"`},
+		{`app in []`, "column 9: empty list after in"},
+		{`age == 7d`, "column 5: operator == cannot be used with age (duration); use < or >"},
+		{`7d != age`, "operator != cannot be used with age (duration); use < or >"},
+		{`title`, "column 6: expected a comparison after title"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.source)
+		if err == nil {
+			t.Errorf("ParseExpr(%q) succeeded, want an error containing %q", tt.source, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseExpr(%q) error = %q, want it to contain %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+// testItem is a history item inserted into the test database
+type testItem struct {
+	id     int
+	title  string
+	pin    string
+	app    string
+	copies int
+	age    time.Duration
+	types  map[string]string
+}
+
+var testItems = []testItem{
+	{1, "hello world", "", "com.apple.Safari", 1, 1 * time.Hour, map[string]string{ContentTypeText: "hello world"}},
+	{2, "Screenshot", "b", "com.apple.Preview", 4, 30 * time.Hour, map[string]string{"public.png": "\x89PNG", ContentTypeText: "shot"}},
+	{3, "https://example.com", "", "com.apple.Safari", 7, 3 * 24 * time.Hour, map[string]string{ContentTypeText: "https://example.com", ContentTypeHTML: "<a>link</a>"}},
+	{4, "", "", "", 2, 10 * 24 * time.Hour, map[string]string{ContentTypeText: "token=abc"}},
+	{5, "image only", "c", "com.apple.Preview", 1, 40 * 24 * time.Hour, map[string]string{"public.png": "\x89PNG"}},
+	{6, "100% done_now", "", "com.googlecode.iterm2", 3, 2 * time.Minute, map[string]string{ContentTypeText: "100% done_now"}},
+}
+
+// openTestRepository creates a database in the standard layout holding
+// testItems, with times relative to now
+func openTestRepository(t *testing.T, now time.Time) *Repository {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "history.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`
+		CREATE TABLE HistoryItem (id INTEGER PRIMARY KEY, title TEXT, pin TEXT, firstCopiedAt REAL,
+			lastCopiedAt REAL, numberOfCopies INTEGER, application TEXT);
+		CREATE TABLE HistoryItemContent (item_id INTEGER, type TEXT, value BLOB);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range testItems {
+		var pin, app interface{}
+		if item.pin != "" {
+			pin = item.pin
+		}
+		if item.app != "" {
+			app = item.app
+		}
+		last := timeToCocoaTimestamp(now.Add(-item.age))
+		if _, err := db.Exec(`INSERT INTO HistoryItem VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			item.id, item.title, pin, last-3600, last, item.copies, app); err != nil {
+			t.Fatal(err)
+		}
+		for typ, value := range item.types {
+			if _, err := db.Exec(`INSERT INTO HistoryItemContent VALUES (?, ?, ?)`, item.id, typ, []byte(value)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return NewRepository(db)
+}
+
+// apply runs Filter.Apply, failing the test on an error
+func apply(t *testing.T, filter Filter, items []HistoryItem) []HistoryItem {
+	t.Helper()
+	result, err := filter.Apply(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func itemIDs(items []HistoryItem) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// TestExprSQLMatchesGo checks that the SQL compiled from an expression,
+// together with its residual conjuncts, selects the same items as
+// evaluating the whole expression in Go
+func TestExprSQLMatchesGo(t *testing.T) {
+	now := time.Now()
+	repo := openTestRepository(t, now)
+	all, err := repo.GetAllItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source   string
+		residual bool
+		want     []int
+	}{
+		{`app == "com.apple.Safari"`, false, []int{1, 3}},
+		{`app == ""`, false, []int{4}},
+		{`app != "com.apple.Safari"`, false, []int{6, 2, 4, 5}},
+		{`app in ["com.apple.Preview", "com.googlecode.iterm2"]`, false, []int{6, 2, 5}},
+		{`copies >= 3 && !pinned`, false, []int{6, 3}},
+		{`pinned`, false, []int{2, 5}},
+		{`pin == "b" || copies == 7`, false, []int{2, 3}},
+		{`title < "i"`, false, []int{6, 1, 2, 3, 4}},
+		{`age < 2d`, false, []int{6, 1, 2}},
+		{`age >= 2d`, false, []int{3, 4, 5}},
+		{`2d > age`, false, []int{6, 1, 2}},
+		{`not age > 1d`, false, []int{6, 1}},
+		{`last > 2024-01-01`, false, []int{6, 1, 2, 3, 4, 5}},
+		{`type == "public.png"`, false, []int{2, 5}},
+		{`type != "public.png"`, false, []int{6, 1, 3, 4}},
+		{`!(type == "public.png")`, false, []int{6, 1, 3, 4}},
+		{`type in ["public.html", "public.png"]`, false, []int{2, 3, 5}},
+		{`text =~ /https?:/`, true, []int{3}},
+		{`text == "shot"`, true, []int{2}},
+		{`title !~ /o/ && copies > 1`, true, []int{4}},
+		{`copies > 5 || text =~ /token/`, true, []int{3, 4}},
+		{`pinned && type !~ /html/`, true, []int{2, 5}},
+		{`type !~ /png/ && copies > 1`, true, []int{6, 3, 4}},
+		{`age < 5d && (title =~ /^h/i || type == "public.png")`, true, []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.source)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.source, err)
+			continue
+		}
+		expr.now = now
+
+		filter := Filter{Where: expr}
+		if got := filter.hasResidual(standardColumns); got != tt.residual {
+			t.Errorf("%s: residual = %v, want %v", tt.source, got, tt.residual)
+		}
+
+		fromSQL, err := repo.GetItems(filter)
+		if err != nil {
+			t.Errorf("%s: GetItems: %v", tt.source, err)
+			continue
+		}
+		inGo := apply(t, filter, all)
+
+		if got := itemIDs(fromSQL); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: SQL selected %v, want %v", tt.source, got, tt.want)
+		}
+		if got := itemIDs(inGo); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Go selected %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestExprPagingWithResidual checks that limits and offsets apply to the
+// items left after the residual conditions, not to the rows of the query
+func TestExprPagingWithResidual(t *testing.T) {
+	now := time.Now()
+	repo := openTestRepository(t, now)
+	all, err := repo.GetAllItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr, err := ParseExpr(`text =~ /o/ && copies < 7`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr.now = now
+
+	for _, page := range []struct{ limit, offset int }{{1, 0}, {2, 0}, {2, 1}, {2, 3}, {0, 2}, {10, 0}} {
+		filter := Filter{Where: expr, Limit: page.limit, Offset: page.offset}
+		fromSQL, err := repo.GetItems(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := itemIDs(apply(t, filter, all))
+		if got := itemIDs(fromSQL); !slices.Equal(got, want) {
+			t.Errorf("limit %d offset %d: got %v, want %v", page.limit, page.offset, got, want)
+		}
+	}
+
+	// Matches in recency order are 6 (100% done_now), 1 (hello world), 2
+	// (shot) and 4 (token=abc), so the second page of two is 2 and 4
+	filter := Filter{Where: expr, Limit: 2, Offset: 2}
+	if got, _ := repo.GetItems(filter); !slices.Equal(itemIDs(got), []int{2, 4}) {
+		t.Errorf("second page = %v, want [2 4]", itemIDs(got))
+	}
+}
+
+func TestFilterTextIsLiteral(t *testing.T) {
+	repo := openTestRepository(t, time.Now())
+
+	for text, want := range map[string][]int{
+		"100%":    {6},
+		"0% d":    {6},
+		"e_n":     {6},
+		"o_w":     nil,
+		`%`:       {6},
+		"HELLO":   {1},
+		"nowhere": nil,
+	} {
+		items, err := repo.GetItems(Filter{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := itemIDs(items); !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+			t.Errorf("Text %q selected %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter narrows down the history items returned by the repository
+type Filter struct {
+	// Since limits results to items last copied strictly after this time
+	Since time.Time
+	// Until limits results to items last copied at or before this time
+	Until time.Time
+	// Applications limits results to items copied from one of these bundle IDs
+	Applications []string
+	// ContentType limits results to items having a content of this type
+	ContentType string
+	// Text limits results to items whose title or text content contains it
+	Text string
+	// PinnedOnly limits results to pinned items
+	PinnedOnly bool
+	// ExcludePinned leaves pinned items out of the results
+	ExcludePinned bool
+	// Where limits results to items satisfying a filter expression
+	Where *Expr
+	// Sort orders the results; the zero value lists the most recent first
+	Sort Sort
+	// After continues a previous page, returning only items ordered after
+	// the cursor
+	After *Cursor
+	// Limit caps the number of returned items; zero means no limit
+	Limit int
+	// Offset skips the given number of items
+	Offset int
+}
+
+// itemColumns maps the logical history item fields to the column names
+// used by one of the Maccy schema flavors
+type itemColumns struct {
+	table          string
+	id             string
+	title          string
+	pin            string
+	firstCopiedAt  string
+	lastCopiedAt   string
+	numberOfCopies string
+	application    string
+	// version is the Core Data optimistic locking column, if any
+	version string
+	// entity and contentEntity name the Core Data entities in Z_PRIMARYKEY
+	entity        string
+	contentEntity string
+
+	contentTable string
+	contentItem  string
+	contentType  string
+	contentValue string
+}
+
+var standardColumns = itemColumns{
+	table:          "HistoryItem",
+	id:             "id",
+	title:          "title",
+	pin:            "pin",
+	firstCopiedAt:  "firstCopiedAt",
+	lastCopiedAt:   "lastCopiedAt",
+	numberOfCopies: "numberOfCopies",
+	application:    "application",
+
+	contentTable: "HistoryItemContent",
+	contentItem:  "item_id",
+	contentType:  "type",
+	contentValue: "value",
+}
+
+var alternativeColumns = itemColumns{
+	table:          "ZHISTORYITEM",
+	id:             "Z_PK",
+	title:          "ZTITLE",
+	pin:            "ZPIN",
+	firstCopiedAt:  "ZFIRSTCOPIEDAT",
+	lastCopiedAt:   "ZLASTCOPIEDAT",
+	numberOfCopies: "ZNUMBEROFCOPIES",
+	application:    "ZAPPLICATION",
+	version:        "Z_OPT",
+	entity:         "HistoryItem",
+	contentEntity:  "HistoryItemContent",
+
+	contentTable: "ZHISTORYITEMCONTENT",
+	contentItem:  "ZITEM",
+	contentType:  "ZTYPE",
+	contentValue: "ZVALUE",
+}
+
+// ItemTables maps every table holding item data, in both schema layouts, to
+// its column referencing the item ID
+func ItemTables() map[string]string {
+	tables := map[string]string{}
+	for _, c := range []itemColumns{standardColumns, alternativeColumns} {
+		tables[c.table] = c.id
+		tables[c.contentTable] = c.contentItem
+	}
+	return tables
+}
+
+// ParseTime accepts RFC 3339 timestamps, plain dates and durations relative
+// to now such as "24h"
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC 3339 time, date or duration, got %q", value)
+}
+
+// selectList returns the column list in the order expected by scanHistoryItems
+func (c itemColumns) selectList() string {
+	return strings.Join([]string{
+		c.id, c.title, c.pin, c.firstCopiedAt, c.lastCopiedAt, c.numberOfCopies, c.application,
+	}, ", ")
+}
+
+// whereClause turns the filter criteria into SQL conditions and their arguments
+func (f Filter) whereClause(c itemColumns) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if !f.Since.IsZero() {
+		// Cocoa timestamps lose precision on the round trip through float64,
+		// so an item copied at Since itself must not compare as later
+		conditions = append(conditions, c.lastCopiedAt+" > ?")
+		args = append(args, timeToCocoaTimestamp(f.Since)+timeTolerance)
+	}
+	if !f.Until.IsZero() {
+		conditions = append(conditions, c.lastCopiedAt+" <= ?")
+		args = append(args, timeToCocoaTimestamp(f.Until))
+	}
+	if len(f.Applications) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Applications)), ", ")
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", c.application, placeholders))
+		for _, app := range f.Applications {
+			args = append(args, app)
+		}
+	}
+	if f.ContentType != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM %s WHERE %s = %s.%s AND %s = ?)",
+			c.contentTable, c.contentItem, c.table, c.id, c.contentType))
+		args = append(args, f.ContentType)
+	}
+	if f.Text != "" {
+		pattern := "%" + escapeLike(f.Text) + "%"
+		conditions = append(conditions, fmt.Sprintf(
+			`(%s LIKE ? ESCAPE '\' OR EXISTS (SELECT 1 FROM %s WHERE %s = %s.%s AND %s = ? AND CAST(%s AS TEXT) LIKE ? ESCAPE '\'))`,
+			c.title, c.contentTable, c.contentItem, c.table, c.id, c.contentType, c.contentValue))
+		args = append(args, pattern, ContentTypeText, pattern)
+	}
+	if f.PinnedOnly {
+		conditions = append(conditions, fmt.Sprintf("%s IS NOT NULL AND %s != ''", c.pin, c.pin))
+	}
+	if f.ExcludePinned {
+		conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s = '')", c.pin, c.pin))
+	}
+	if f.Where != nil {
+		conds, condArgs, _ := f.Where.conditions(c)
+		conditions = append(conditions, conds...)
+		args = append(args, condArgs...)
+	}
+	if f.After != nil {
+		cond, condArgs := f.After.condition(c)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the LIKE wildcards so text matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike quotes s for use in a LIKE pattern with ESCAPE '\'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// buildQuery turns a filter into a SQL query and its arguments
+func (f Filter) buildQuery(c itemColumns) (string, []interface{}) {
+	where, args := f.whereClause(c)
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s", c.selectList(), c.table, where)
+	query += f.Sort.orderBy(c)
+
+	// Paging is left to the caller when part of the filter runs in Go
+	if f.hasResidual(c) {
+		return query, args
+	}
+
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	} else if f.Offset > 0 {
+		query += " LIMIT -1"
+	}
+	if f.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", f.Offset)
+	}
+
+	return query, args
+}
+
+// hasResidual reports whether part of the filter cannot be expressed in SQL
+// and has to be evaluated on the scanned items
+func (f Filter) hasResidual(c itemColumns) bool {
+	if f.Where == nil {
+		return false
+	}
+	_, _, residual := f.Where.conditions(c)
+	return residual
+}
+
+// Matches reports whether an item satisfies the filter criteria, mirroring
+// the SQL built by whereClause. Limit and Offset are ignored.
+func (f Filter) Matches(item HistoryItem) bool {
+	if !f.Since.IsZero() && timeToCocoaTimestamp(item.LastCopiedAt) <= timeToCocoaTimestamp(f.Since)+timeTolerance {
+		return false
+	}
+	if !f.Until.IsZero() && item.LastCopiedAt.After(f.Until) {
+		return false
+	}
+	if len(f.Applications) > 0 && !slices.Contains(f.Applications, item.Application) {
+		return false
+	}
+	if f.ContentType != "" && !slices.ContainsFunc(item.Contents, func(c Content) bool {
+		return c.Type == f.ContentType
+	}) {
+		return false
+	}
+	if f.Text != "" {
+		text := strings.ToLower(f.Text)
+		if !strings.Contains(strings.ToLower(item.Title), text) && !strings.Contains(strings.ToLower(item.Text()), text) {
+			return false
+		}
+	}
+	if f.PinnedOnly && item.Pin == "" {
+		return false
+	}
+	if f.ExcludePinned && item.Pin != "" {
+		return false
+	}
+	if f.Where != nil && !f.Where.Match(item) {
+		return false
+	}
+	return true
+}
+
+// Apply filters, orders and pages items held in memory the same way the
+// repository does in SQL. Like GetItems, it returns ErrCursorItemGone for a
+// title cursor whose item is not among items.
+func (f Filter) Apply(items []HistoryItem) ([]HistoryItem, error) {
+	var result []HistoryItem
+	for _, item := range items {
+		if f.Matches(item) {
+			result = append(result, item)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return f.Sort.less(result[i], result[j])
+	})
+
+	if f.After != nil && !f.After.resolved() {
+		// A title cursor whose item is not among items cannot be placed
+		cursor := *f.After
+		for _, item := range items {
+			if item.ID == cursor.ID {
+				cursor.resolve(item)
+			}
+		}
+		if !cursor.resolved() {
+			return nil, ErrCursorItemGone
+		}
+		f.After = &cursor
+	}
+	if f.After != nil {
+		after := result[:0]
+		for _, item := range result {
+			if f.After.after(item) {
+				after = append(after, item)
+			}
+		}
+		result = after
+	}
+
+	return f.page(result), nil
+}
+
+// page applies Offset and Limit to items already filtered and ordered
+func (f Filter) page(result []HistoryItem) []HistoryItem {
+	if f.Offset > 0 {
+		result = result[min(f.Offset, len(result)):]
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[:f.Limit]
+	}
+	return result
+}
+
+// NextCursor returns the cursor continuing after a page of items returned
+// for the filter, or nil if the page was the last one
+func (f Filter) NextCursor(items []HistoryItem) *Cursor {
+	if f.Limit <= 0 || len(items) < f.Limit {
+		return nil
+	}
+	return NewCursor(f.Sort, items[len(items)-1])
+}
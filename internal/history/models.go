@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -19,6 +20,26 @@ type HistoryItem struct {
 	Contents       []Content `json:"contents,omitempty"`
 }
 
+// Well-known pasteboard types stored by Maccy
+const (
+	ContentTypeText    = "public.utf8-plain-text"
+	ContentTypeHTML    = "public.html"
+	ContentTypeRTF     = "public.rtf"
+	ContentTypePNG     = "public.png"
+	ContentTypeTIFF    = "public.tiff"
+	ContentTypeFileURL = "public.file-url"
+)
+
+// Text returns the plain text content of the item, if any
+func (h HistoryItem) Text() string {
+	for _, content := range h.Contents {
+		if content.Type == ContentTypeText {
+			return string(content.Value)
+		}
+	}
+	return ""
+}
+
 // Content represents the content of a history item
 type Content struct {
 	Type  string `json:"type"`
@@ -36,7 +57,7 @@ func (c Content) MarshalJSON() ([]byte, error) {
 	// Special handling for different content types
 	var valueStr string
 	switch c.Type {
-	case "public.utf8-plain-text":
+	case ContentTypeText:
 		// For text content, convert directly to string
 		valueStr = string(c.Value)
 	default:
@@ -50,6 +71,30 @@ func (c Content) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON reverses MarshalJSON, decoding base64 for binary content
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var alias struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+
+	c.Type = alias.Type
+	if alias.Type == ContentTypeText {
+		c.Value = []byte(alias.Value)
+		return nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(alias.Value)
+	if err != nil {
+		return fmt.Errorf("error decoding %s content: %w", alias.Type, err)
+	}
+	c.Value = value
+	return nil
+}
+
 // NullableHistoryItem is used for scanning SQL results with potential NULL values
 type NullableHistoryItem struct {
 	ID             int
@@ -103,3 +148,9 @@ func cocoaTimestampToTime(timestamp float64) time.Time {
 	// Add duration to reference date
 	return referenceDate.Add(duration)
 }
+
+// timeToCocoaTimestamp converts a Go time.Time to a Cocoa/Core Data timestamp
+func timeToCocoaTimestamp(t time.Time) float64 {
+	referenceDate := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	return t.Sub(referenceDate).Seconds()
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/table"
 )
 
 // Printer handles printing of history items
@@ -16,42 +18,9 @@ func NewPrinter(items []HistoryItem) *Printer {
 	return &Printer{items: items}
 }
 
-// PrintItems prints history items in a formatted table
-func (p *Printer) PrintItems() {
-	if len(p.items) == 0 {
-		fmt.Println("No items found.")
-		return
-	}
-
-	fmt.Printf("%-5s %-20s %-3s %-30s %-30s %-5s %-20s\n",
-		"ID", "Title", "Pin", "First Copied", "Last Copied", "Count", "Application")
-	fmt.Println(strings.Repeat("-", 120))
-
-	for _, item := range p.items {
-		titleStr := item.Title
-		if len(titleStr) > 20 {
-			titleStr = titleStr[:17] + "..."
-		}
-
-		pinStr := item.Pin
-		if pinStr == "" {
-			pinStr = "-"
-		}
-
-		appStr := item.Application
-		if appStr == "" {
-			appStr = "<unknown>"
-		}
-
-		firstCopiedStr := formatTime(item.FirstCopiedAt)
-		lastCopiedStr := formatTime(item.LastCopiedAt)
-
-		fmt.Printf("%-5d %-20s %-3s %-30s %-30s %-5d %-20s\n",
-			item.ID, titleStr, pinStr, firstCopiedStr, lastCopiedStr, item.NumberOfCopies, appStr)
-	}
-}
-
-func formatTime(t time.Time) string {
+// FormatTime renders a copy time in local time for display, or <never> for
+// unset times
+func FormatTime(t time.Time) string {
 	if t.IsZero() {
 		return "<never>"
 	}
@@ -62,3 +31,45 @@ func formatTime(t time.Time) string {
 	// Prefer local time for display
 	return t.Local().Format("2006-01-02 15:04:05")
 }
+
+// PrintDetails prints all fields and decoded contents of each item
+func (p *Printer) PrintDetails() {
+	for i, item := range p.items {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(FormatDetails(item))
+	}
+}
+
+// FormatDetails renders all fields of an item followed by its decoded
+// contents. Control characters are escaped so copied text cannot drive the
+// terminal.
+func FormatDetails(item HistoryItem) string {
+	var b strings.Builder
+
+	pinStr := item.Pin
+	if pinStr == "" {
+		pinStr = "-"
+	}
+	appStr := item.Application
+	if appStr == "" {
+		appStr = "<unknown>"
+	}
+
+	fmt.Fprintf(&b, "ID:           %d\n", item.ID)
+	fmt.Fprintf(&b, "Title:        %s\n", table.Escape(item.Title))
+	fmt.Fprintf(&b, "Pin:          %s\n", table.Escape(pinStr))
+	fmt.Fprintf(&b, "Application:  %s\n", table.Escape(appStr))
+	fmt.Fprintf(&b, "First Copied: %s\n", FormatTime(item.FirstCopiedAt))
+	fmt.Fprintf(&b, "Last Copied:  %s\n", FormatTime(item.LastCopiedAt))
+	fmt.Fprintf(&b, "Copies:       %d\n", item.NumberOfCopies)
+
+	for _, content := range item.Contents {
+		fmt.Fprintf(&b, "\n--- %s (%d bytes) ---\n", table.Escape(content.Type), len(content.Value))
+		b.WriteString(table.EscapeText(strings.TrimRight(content.DecodedText(), "\n")))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
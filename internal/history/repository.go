@@ -1,21 +1,86 @@
 package history
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/gkwa/sunlitsparrow/internal/logger"
 )
 
+// ErrItemNotFound is returned when no history item has the requested ID
+var ErrItemNotFound = errors.New("history item not found")
+
 // Repository handles database operations for history items
 type Repository struct {
-	db *sql.DB
+	db           *sql.DB
+	log          *slog.Logger
+	skipContents bool
 }
 
 // NewRepository creates a new history repository
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, log: logger.Default()}
+}
+
+// WithContext returns a copy of the repository that logs to the logger
+// carried by ctx, so queries can be traced back to the command or request
+// that made them
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	scoped := *r
+	scoped.log = logger.FromContext(ctx)
+	return &scoped
+}
+
+// query runs a query, tracing it with its arguments and duration
+func (r *Repository) query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := r.db.Query(query, args...)
+	r.trace(query, args, start, err)
+	return rows, err
+}
+
+// queryRow runs a query expected to return at most one row, tracing it
+// like query
+func (r *Repository) queryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := r.db.QueryRow(query, args...)
+	r.trace(query, args, start, row.Err())
+	return row
+}
+
+func (r *Repository) trace(query string, args []interface{}, start time.Time, err error) {
+	traceQuery(r.log, query, args, start, err)
+}
+
+// traceQuery logs an executed statement at trace level with its arguments,
+// leaving out the bytes of BLOB arguments
+func traceQuery(log *slog.Logger, query string, args []interface{}, start time.Time, err error) {
+	attrs := []any{"query", strings.Join(strings.Fields(query), " "), "duration", time.Since(start)}
+	if len(args) > 0 {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			if b, ok := arg.([]byte); ok {
+				arg = fmt.Sprintf("<%d bytes>", len(b))
+			}
+			values[i] = arg
+		}
+		attrs = append(attrs, "args", values)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	log.Log(context.Background(), logger.LevelTrace, "Executed query", attrs...)
+}
+
+// SkipContents stops listings from loading item contents, saving a query
+// per item when only the item fields are needed
+func (r *Repository) SkipContents(skip bool) {
+	r.skipContents = skip
 }
 
 // GetRecentItems retrieves the most recent history items
@@ -26,13 +91,13 @@ func (r *Repository) GetRecentItems(limit int) ([]HistoryItem, error) {
 		return items, nil
 	}
 
-	logger.Debug("Standard schema query failed, trying alternative: %v", err)
+	r.log.Debug("Standard schema query failed, trying alternative", "error", err)
 	items, err = r.tryAlternativeSchema(limit)
 	if err == nil {
 		return items, nil
 	}
 
-	logger.Debug("Alternative schema query failed, trying dynamic approach: %v", err)
+	r.log.Debug("Alternative schema query failed, trying dynamic approach", "error", err)
 	return r.tryDynamicSchema(limit)
 }
 
@@ -44,16 +109,55 @@ func (r *Repository) GetAllItems() ([]HistoryItem, error) {
 		return items, nil
 	}
 
-	logger.Debug("Standard schema query failed, trying alternative: %v", err)
+	r.log.Debug("Standard schema query failed, trying alternative", "error", err)
 	items, err = r.tryAlternativeSchema(0)
 	if err == nil {
 		return items, nil
 	}
 
-	logger.Debug("Alternative schema query failed, trying dynamic approach: %v", err)
+	r.log.Debug("Alternative schema query failed, trying dynamic approach", "error", err)
 	return r.tryDynamicSchema(0)
 }
 
+// ErrCursorItemGone is returned when the item a title cursor continues after
+// was deleted
+var ErrCursorItemGone = errors.New("the last item of the previous page no longer exists; start again without a cursor")
+
+// GetItems retrieves history items matching the given filter
+func (r *Repository) GetItems(filter Filter) ([]HistoryItem, error) {
+	if filter.After != nil && !filter.After.resolved() {
+		item, err := r.GetItem(filter.After.ID)
+		if errors.Is(err, ErrItemNotFound) {
+			return nil, ErrCursorItemGone
+		}
+		if err != nil {
+			return nil, err
+		}
+		cursor := *filter.After
+		cursor.resolve(item)
+		filter.After = &cursor
+	}
+
+	items, err := r.queryItems(standardColumns, filter)
+	if err == nil {
+		return items, nil
+	}
+
+	r.log.Debug("Standard schema query failed, trying alternative", "error", err)
+	return r.queryItems(alternativeColumns, filter)
+}
+
+// GetItem retrieves a single history item by ID
+func (r *Repository) GetItem(id int) (HistoryItem, error) {
+	item, err := r.queryItem(standardColumns, id)
+	if err == nil || errors.Is(err, ErrItemNotFound) {
+		return item, err
+	}
+
+	r.log.Debug("Standard schema query failed, trying alternative", "error", err)
+	return r.queryItem(alternativeColumns, id)
+}
+
 // GetPinnedItems retrieves all pinned history items
 func (r *Repository) GetPinnedItems() ([]HistoryItem, error) {
 	// Try standard schema query for pinned items
@@ -63,17 +167,17 @@ func (r *Repository) GetPinnedItems() ([]HistoryItem, error) {
 		WHERE pin IS NOT NULL AND pin != ''
 		ORDER BY lastCopiedAt DESC
 	`
-	rows, err := r.db.Query(query)
+	rows, err := r.query(query)
 	if err != nil {
 		// Try alternative schema query
-		logger.Debug("Standard schema query for pins failed, trying alternative: %v", err)
+		r.log.Debug("Standard schema query for pins failed, trying alternative", "error", err)
 		query = `
 			SELECT Z_PK, ZTITLE, ZPIN, ZFIRSTCOPIEDAT, ZLASTCOPIEDAT, ZNUMBEROFCOPIES, ZAPPLICATION
 			FROM ZHISTORYITEM
 			WHERE ZPIN IS NOT NULL AND ZPIN != ''
 			ORDER BY ZLASTCOPIEDAT DESC
 		`
-		rows, err = r.db.Query(query)
+		rows, err = r.query(query)
 		if err != nil {
 			return nil, err
 		}
@@ -86,14 +190,14 @@ func (r *Repository) GetPinnedItems() ([]HistoryItem, error) {
 // GetItemContents retrieves contents for a specific history item
 func (r *Repository) GetItemContents(itemID int) ([]Content, error) {
 	// Try standard schema
-	contentRows, err := r.db.Query(`
+	contentRows, err := r.query(`
 		SELECT type, value
 		FROM HistoryItemContent
 		WHERE item_id = ?
 	`, itemID)
 	if err != nil {
 		// Try alternative schema
-		contentRows, err = r.db.Query(`
+		contentRows, err = r.query(`
 			SELECT ZTYPE, ZVALUE
 			FROM ZHISTORYITEMCONTENT
 			WHERE ZITEM = ?
@@ -134,7 +238,7 @@ func (r *Repository) tryStandardSchema(limit int) ([]HistoryItem, error) {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err := r.db.Query(query)
+	rows, err := r.query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +258,7 @@ func (r *Repository) tryAlternativeSchema(limit int) ([]HistoryItem, error) {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err := r.db.Query(query)
+	rows, err := r.query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +270,7 @@ func (r *Repository) tryAlternativeSchema(limit int) ([]HistoryItem, error) {
 func (r *Repository) tryDynamicSchema(limit int) ([]HistoryItem, error) {
 	// Check if HistoryItem table exists
 	var tableExists bool
-	err := r.db.QueryRow(`
+	err := r.queryRow(`
 		SELECT COUNT(*) > 0 FROM sqlite_master
 		WHERE type='table' AND name='HistoryItem'
 	`).Scan(&tableExists)
@@ -179,7 +283,7 @@ func (r *Repository) tryDynamicSchema(limit int) ([]HistoryItem, error) {
 	}
 
 	// Get column names
-	rows, err := r.db.Query(`SELECT * FROM HistoryItem LIMIT 1`)
+	rows, err := r.query(`SELECT * FROM HistoryItem LIMIT 1`)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +293,7 @@ func (r *Repository) tryDynamicSchema(limit int) ([]HistoryItem, error) {
 		return nil, err
 	}
 
-	logger.Debug("HistoryItem columns: %s", strings.Join(cols, ", "))
+	r.log.Debug("Detected columns", "table", "HistoryItem", "columns", strings.Join(cols, ", "))
 
 	// Build a dynamic query based on actual column names
 	columnsNeeded := map[string]string{
@@ -226,7 +330,7 @@ func (r *Repository) tryDynamicSchema(limit int) ([]HistoryItem, error) {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err = r.db.Query(query)
+	rows, err = r.query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -250,16 +354,20 @@ func (r *Repository) scanHistoryItems(rows *sql.Rows) ([]HistoryItem, error) {
 			&nullableItem.NumberOfCopies,
 			&nullableItem.Application,
 		); err != nil {
-			logger.Debug("Error scanning row: %v", err)
+			r.log.Debug("Error scanning row", "error", err)
 			continue
 		}
 
 		item := nullableItem.ToHistoryItem()
+		if r.skipContents {
+			items = append(items, item)
+			continue
+		}
 
 		// Get contents for this item
 		contents, err := r.GetItemContents(item.ID)
 		if err != nil {
-			logger.Debug("Error getting contents for item %d: %v", item.ID, err)
+			r.log.Debug("Error getting contents", "item_id", item.ID, "error", err)
 		} else {
 			item.Contents = contents
 		}
@@ -269,3 +377,51 @@ func (r *Repository) scanHistoryItems(rows *sql.Rows) ([]HistoryItem, error) {
 
 	return items, nil
 }
+
+func (r *Repository) queryItems(columns itemColumns, filter Filter) ([]HistoryItem, error) {
+	query, args := filter.buildQuery(columns)
+	rows, err := r.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items, err := r.scanHistoryItems(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Conditions of a filter expression that have no SQL equivalent are
+	// evaluated here, followed by the paging the query had to leave out
+	if filter.hasResidual(columns) {
+		filtered := items[:0]
+		for _, item := range items {
+			if filter.Where.Match(item) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filter.page(filtered)
+	}
+
+	return items, nil
+}
+
+func (r *Repository) queryItem(columns itemColumns, id int) (HistoryItem, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", columns.selectList(), columns.table, columns.id)
+
+	rows, err := r.query(query, id)
+	if err != nil {
+		return HistoryItem{}, err
+	}
+	defer rows.Close()
+
+	items, err := r.scanHistoryItems(rows)
+	if err != nil {
+		return HistoryItem{}, err
+	}
+	if len(items) == 0 {
+		return HistoryItem{}, fmt.Errorf("%w: %d", ErrItemNotFound, id)
+	}
+
+	return items[0], nil
+}
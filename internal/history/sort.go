@@ -0,0 +1,273 @@
+package history
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SortField is an item attribute results can be ordered by
+type SortField string
+
+const (
+	SortLastCopiedAt   SortField = "lastCopiedAt"
+	SortFirstCopiedAt  SortField = "firstCopiedAt"
+	SortNumberOfCopies SortField = "numberOfCopies"
+	SortTitle          SortField = "title"
+	// SortSize orders by the combined size of an item's contents in bytes
+	SortSize SortField = "size"
+)
+
+// SortFields lists the supported sort fields
+var SortFields = []SortField{SortLastCopiedAt, SortFirstCopiedAt, SortNumberOfCopies, SortTitle, SortSize}
+
+// sortAliases are short names accepted by ParseSort
+var sortAliases = map[string]SortField{
+	"last":   SortLastCopiedAt,
+	"first":  SortFirstCopiedAt,
+	"copies": SortNumberOfCopies,
+}
+
+// timeTolerance is how far apart, in seconds, two Cocoa timestamps may be
+// and still be treated as equal, absorbing float64 rounding in the
+// conversion to and from time.Time
+const timeTolerance = 1e-6
+
+// Sort orders results by a field, breaking ties by item ID in the same
+// direction. The zero value orders by lastCopiedAt, most recent first.
+type Sort struct {
+	Field     SortField
+	Ascending bool
+}
+
+// ParseSort parses "field" or "field:asc" / "field:desc". Without a
+// direction, titles sort ascending and everything else descending.
+func ParseSort(spec string) (Sort, error) {
+	if spec == "" {
+		return Sort{}, nil
+	}
+
+	name, direction, _ := strings.Cut(spec, ":")
+	var sort Sort
+	for _, field := range SortFields {
+		if strings.EqualFold(name, string(field)) {
+			sort.Field = field
+		}
+	}
+	if field, ok := sortAliases[strings.ToLower(name)]; ok {
+		sort.Field = field
+	}
+	if sort.Field == "" {
+		names := make([]string, len(SortFields))
+		for i, field := range SortFields {
+			names[i] = string(field)
+		}
+		return Sort{}, fmt.Errorf("unknown sort field %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+
+	switch strings.ToLower(direction) {
+	case "":
+		sort.Ascending = sort.Field == SortTitle
+	case "asc":
+		sort.Ascending = true
+	case "desc":
+		sort.Ascending = false
+	default:
+		return Sort{}, fmt.Errorf("unknown sort direction %q (expected asc or desc)", direction)
+	}
+	return sort, nil
+}
+
+func (s Sort) field() SortField {
+	if s.Field == "" {
+		return SortLastCopiedAt
+	}
+	return s.Field
+}
+
+// String returns the sort in the form accepted by ParseSort
+func (s Sort) String() string {
+	if s.Ascending {
+		return string(s.field()) + ":asc"
+	}
+	return string(s.field()) + ":desc"
+}
+
+// column returns the SQL expression of the sort field
+func (s Sort) column(c itemColumns) string {
+	switch s.field() {
+	case SortFirstCopiedAt:
+		return c.firstCopiedAt
+	case SortNumberOfCopies:
+		return c.numberOfCopies
+	case SortTitle:
+		return "COALESCE(" + c.title + ", '')"
+	case SortSize:
+		return fmt.Sprintf("(SELECT COALESCE(SUM(LENGTH(CAST(%s AS BLOB))), 0) FROM %s WHERE %s = %s.%s)",
+			c.contentValue, c.contentTable, c.contentItem, c.table, c.id)
+	}
+	return c.lastCopiedAt
+}
+
+// orderBy returns the ORDER BY clause of the sort
+func (s Sort) orderBy(c itemColumns) string {
+	direction := "DESC"
+	if s.Ascending {
+		direction = "ASC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, %s.%s %s", s.column(c), direction, c.table, c.id, direction)
+}
+
+// value returns the sort key of an item as it compares in SQL: Cocoa
+// timestamps and numbers as float64, titles as strings
+func (s Sort) value(item HistoryItem) interface{} {
+	switch s.field() {
+	case SortFirstCopiedAt:
+		return timeToCocoaTimestamp(item.FirstCopiedAt)
+	case SortNumberOfCopies:
+		return float64(item.NumberOfCopies)
+	case SortTitle:
+		return item.Title
+	case SortSize:
+		size := 0
+		for _, content := range item.Contents {
+			size += len(content.Value)
+		}
+		return float64(size)
+	}
+	return timeToCocoaTimestamp(item.LastCopiedAt)
+}
+
+// tolerance returns the distance within which sort keys are equal
+func (s Sort) tolerance() float64 {
+	if s.field() == SortLastCopiedAt || s.field() == SortFirstCopiedAt {
+		return timeTolerance
+	}
+	return 0
+}
+
+// compare orders two positions, each a sort key and an item ID, returning a
+// negative number if a comes first
+func (s Sort) compare(aValue interface{}, aID int, bValue interface{}, bID int) int {
+	c := 0
+	switch a := aValue.(type) {
+	case string:
+		c = strings.Compare(a, bValue.(string))
+	case float64:
+		if b := bValue.(float64); math.Abs(a-b) > s.tolerance() {
+			c = compareFloat(a, b)
+		}
+	}
+	if c == 0 {
+		c = compareFloat(float64(aID), float64(bID))
+	}
+	if !s.Ascending {
+		c = -c
+	}
+	return c
+}
+
+// less reports whether item a is ordered before item b
+func (s Sort) less(a, b HistoryItem) bool {
+	return s.compare(s.value(a), a.ID, s.value(b), b.ID) < 0
+}
+
+// Cursor marks the last item of a page so the next page can continue after
+// it even when items are added in the meantime
+type Cursor struct {
+	Sort Sort
+	// Value is the sort key of the item. Titles are not stored in tokens, so
+	// for cursors parsed from a title-sorted token it is nil until resolved
+	// from the item itself.
+	Value interface{}
+	ID    int
+}
+
+// cursorToken is the serialized form of a cursor
+type cursorToken struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v,omitempty"`
+	ID    int         `json:"id"`
+}
+
+// NewCursor returns the cursor positioned at an item
+func NewCursor(sort Sort, item HistoryItem) *Cursor {
+	return &Cursor{Sort: sort, Value: sort.value(item), ID: item.ID}
+}
+
+// String encodes the cursor as an opaque token. Tokens only hold numeric
+// sort keys: a title could carry clipboard contents that redaction hides
+// from the page itself, so title cursors hold just the item ID.
+func (c *Cursor) String() string {
+	t := cursorToken{Sort: c.Sort.String(), ID: c.ID}
+	if c.Sort.field() != SortTitle {
+		t.Value = c.Value
+	}
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token returned with a previous page. The token must
+// have been created for the same sort order.
+func ParseCursor(token string, sort Sort) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var t cursorToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	if t.Sort != sort.String() {
+		return nil, fmt.Errorf("cursor was created for sort order %s, not %s", t.Sort, sort)
+	}
+
+	valid := false
+	switch t.Value.(type) {
+	case nil:
+		valid = sort.field() == SortTitle
+	case float64:
+		valid = sort.field() != SortTitle
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &Cursor{Sort: sort, Value: t.Value, ID: t.ID}, nil
+}
+
+// resolved reports whether the cursor has its sort key
+func (c *Cursor) resolved() bool {
+	return c.Value != nil
+}
+
+// resolve sets the sort key of a title cursor from its item
+func (c *Cursor) resolve(item HistoryItem) {
+	c.Value = c.Sort.value(item)
+}
+
+// condition returns the SQL condition selecting items after the cursor
+func (c *Cursor) condition(cols itemColumns) (string, []interface{}) {
+	column := c.Sort.column(cols)
+	id := cols.table + "." + cols.id
+
+	low, high := c.Value, c.Value
+	if v, ok := c.Value.(float64); ok {
+		low, high = v-c.Sort.tolerance(), v+c.Sort.tolerance()
+	}
+
+	if c.Sort.Ascending {
+		return fmt.Sprintf("(%s > ? OR (%s BETWEEN ? AND ? AND %s > ?))", column, column, id),
+			[]interface{}{high, low, high, c.ID}
+	}
+	return fmt.Sprintf("(%s < ? OR (%s BETWEEN ? AND ? AND %s < ?))", column, column, id),
+		[]interface{}{low, low, high, c.ID}
+}
+
+// after reports whether an item is ordered after the cursor
+func (c *Cursor) after(item HistoryItem) bool {
+	return c.Sort.compare(c.Sort.value(item), item.ID, c.Value, c.ID) > 0
+}
@@ -0,0 +1,141 @@
+package history
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTitleCursorHidesTitle(t *testing.T) {
+	sort := Sort{Field: SortTitle, Ascending: true}
+	token := NewCursor(sort, HistoryItem{ID: 7, Title: "password hunter2"}).String()
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("cursor token %s holds the title", data)
+	}
+
+	cursor, err := ParseCursor(token, sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ID != 7 || cursor.resolved() {
+		t.Errorf("ParseCursor = %+v, want item 7 without a value", cursor)
+	}
+}
+
+// TestCursorPaging pages through every sort order with cursors and checks
+// the pages add up to the full, unpaged listing
+func TestCursorPaging(t *testing.T) {
+	repo := openTestRepository(t, time.Now())
+	all, err := repo.GetAllItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range SortFields {
+		for _, ascending := range []bool{true, false} {
+			sort := Sort{Field: field, Ascending: ascending}
+			want := itemIDs(apply(t, Filter{Sort: sort}, all))
+
+			var fromSQL, inGo []int
+			var token string
+			for page := 0; page < len(all); page++ {
+				filter := Filter{Sort: sort, Limit: 2}
+				if token != "" {
+					if filter.After, err = ParseCursor(token, sort); err != nil {
+						t.Fatal(err)
+					}
+				}
+				items, err := repo.GetItems(filter)
+				if err != nil {
+					t.Fatalf("%s: %v", sort, err)
+				}
+				fromSQL = append(fromSQL, itemIDs(items)...)
+				inGo = append(inGo, itemIDs(apply(t, filter, all))...)
+
+				cursor := filter.NextCursor(items)
+				if cursor == nil {
+					break
+				}
+				token = cursor.String()
+			}
+
+			if !slices.Equal(fromSQL, want) {
+				t.Errorf("%s: SQL pages = %v, want %v", sort, fromSQL, want)
+			}
+			if !slices.Equal(inGo, want) {
+				t.Errorf("%s: Go pages = %v, want %v", sort, inGo, want)
+			}
+		}
+	}
+}
+
+func TestTitleCursorItemDeleted(t *testing.T) {
+	repo := openTestRepository(t, time.Now())
+	sort := Sort{Field: SortTitle, Ascending: true}
+
+	cursor, err := ParseCursor(NewCursor(sort, HistoryItem{ID: 99}).String(), sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetItems(Filter{Sort: sort, After: cursor}); !errors.Is(err, ErrCursorItemGone) {
+		t.Errorf("GetItems after a deleted item = %v, want ErrCursorItemGone", err)
+	}
+
+	all, err := repo.GetAllItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Filter{Sort: sort, After: cursor}).Apply(all); !errors.Is(err, ErrCursorItemGone) {
+		t.Errorf("Apply after a deleted item = %v, want ErrCursorItemGone", err)
+	}
+}
+
+// TestSinceBoundaryPages checks that an item copied exactly at Since is left
+// out by the query itself, so a page still holds Limit items
+func TestSinceBoundaryPages(t *testing.T) {
+	repo := openTestRepository(t, time.Now())
+
+	// Store timestamps that come back slightly earlier after the round trip
+	// through time.Time, so the plain SQL comparison would keep the boundary
+	for i, item := range testItems {
+		stamp := 750000000.1 - float64(i)*3600
+		for timeToCocoaTimestamp(cocoaTimestampToTime(stamp)) >= stamp {
+			stamp += 1e-7
+		}
+		if _, err := repo.db.Exec("UPDATE HistoryItem SET lastCopiedAt = ? WHERE id = ?", stamp, item.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := repo.GetAllItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Oldest first, the boundary item would take the first place of the page
+	sort := Sort{Field: SortLastCopiedAt, Ascending: true}
+	all = apply(t, Filter{Sort: sort}, all)
+	for i, boundary := range all {
+		filter := Filter{Since: boundary.LastCopiedAt, Sort: sort, Limit: 2}
+		want := itemIDs(all[i+1 : min(i+3, len(all))])
+
+		items, err := repo.GetItems(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := itemIDs(items); !slices.Equal(got, want) {
+			t.Errorf("since item %d: SQL page = %v, want %v", boundary.ID, got, want)
+		}
+		if got := itemIDs(apply(t, filter, all)); !slices.Equal(got, want) {
+			t.Errorf("since item %d: Go page = %v, want %v", boundary.ID, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package history
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Stats summarizes the clipboard history
+type Stats struct {
+	TotalItems   int               `json:"totalItems" yaml:"totalItems"`
+	PinnedItems  int               `json:"pinnedItems" yaml:"pinnedItems"`
+	TotalCopies  int               `json:"totalCopies" yaml:"totalCopies"`
+	OldestItem   time.Time         `json:"oldestItem" yaml:"oldestItem"`
+	NewestItem   time.Time         `json:"newestItem" yaml:"newestItem"`
+	Applications []ApplicationStat `json:"applications" yaml:"applications"`
+	ContentTypes []ContentTypeStat `json:"contentTypes" yaml:"contentTypes"`
+}
+
+// ApplicationStat counts items copied from a single application
+type ApplicationStat struct {
+	Application string `json:"application" yaml:"application"`
+	Items       int    `json:"items" yaml:"items"`
+	Copies      int    `json:"copies" yaml:"copies"`
+}
+
+// ContentTypeStat counts contents of a single pasteboard type
+type ContentTypeStat struct {
+	Type  string `json:"type" yaml:"type"`
+	Count int    `json:"count" yaml:"count"`
+	Bytes int64  `json:"bytes" yaml:"bytes"`
+}
+
+// GetStats computes summary statistics over the whole history
+func (r *Repository) GetStats() (*Stats, error) {
+	stats, err := r.queryStats(standardColumns)
+	if err == nil {
+		return stats, nil
+	}
+
+	return r.queryStats(alternativeColumns)
+}
+
+func (r *Repository) queryStats(c itemColumns) (*Stats, error) {
+	var stats Stats
+	var totalCopies sql.NullInt64
+	var oldest, newest sql.NullFloat64
+
+	err := r.queryRow(fmt.Sprintf(`
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN %s IS NOT NULL AND %s != '' THEN 1 ELSE 0 END), 0),
+			SUM(%s), MIN(%s), MAX(%s)
+		FROM %s
+	`, c.pin, c.pin, c.numberOfCopies, c.firstCopiedAt, c.lastCopiedAt, c.table)).Scan(
+		&stats.TotalItems, &stats.PinnedItems, &totalCopies, &oldest, &newest)
+	if err != nil {
+		return nil, err
+	}
+
+	if totalCopies.Valid {
+		stats.TotalCopies = int(totalCopies.Int64)
+	}
+	if oldest.Valid {
+		stats.OldestItem = cocoaTimestampToTime(oldest.Float64)
+	}
+	if newest.Valid {
+		stats.NewestItem = cocoaTimestampToTime(newest.Float64)
+	}
+
+	appRows, err := r.query(fmt.Sprintf(`
+		SELECT COALESCE(%s, ''), COUNT(*), COALESCE(SUM(%s), 0)
+		FROM %s
+		GROUP BY 1
+		ORDER BY 2 DESC
+	`, c.application, c.numberOfCopies, c.table))
+	if err != nil {
+		return nil, err
+	}
+	defer appRows.Close()
+
+	for appRows.Next() {
+		var stat ApplicationStat
+		if err := appRows.Scan(&stat.Application, &stat.Items, &stat.Copies); err != nil {
+			return nil, err
+		}
+		stats.Applications = append(stats.Applications, stat)
+	}
+
+	typeRows, err := r.query(fmt.Sprintf(`
+		SELECT COALESCE(%s, ''), COUNT(*), COALESCE(SUM(LENGTH(%s)), 0)
+		FROM %s
+		GROUP BY 1
+		ORDER BY 2 DESC
+	`, c.contentType, c.contentValue, c.contentTable))
+	if err != nil {
+		return nil, err
+	}
+	defer typeRows.Close()
+
+	for typeRows.Next() {
+		var stat ContentTypeStat
+		if err := typeRows.Scan(&stat.Type, &stat.Count, &stat.Bytes); err != nil {
+			return nil, err
+		}
+		stats.ContentTypes = append(stats.ContentTypes, stat)
+	}
+
+	return &stats, nil
+}
+
+// Summarize computes the statistics of GetStats over the given items, such
+// as those selected by a filter
+func Summarize(items []HistoryItem) *Stats {
+	var stats Stats
+	apps := map[string]*ApplicationStat{}
+	types := map[string]*ContentTypeStat{}
+
+	for _, item := range items {
+		stats.TotalItems++
+		if item.Pin != "" {
+			stats.PinnedItems++
+		}
+		stats.TotalCopies += item.NumberOfCopies
+		if stats.OldestItem.IsZero() || item.FirstCopiedAt.Before(stats.OldestItem) {
+			stats.OldestItem = item.FirstCopiedAt
+		}
+		if item.LastCopiedAt.After(stats.NewestItem) {
+			stats.NewestItem = item.LastCopiedAt
+		}
+
+		app, ok := apps[item.Application]
+		if !ok {
+			app = &ApplicationStat{Application: item.Application}
+			apps[item.Application] = app
+		}
+		app.Items++
+		app.Copies += item.NumberOfCopies
+
+		for _, content := range item.Contents {
+			contentType, ok := types[content.Type]
+			if !ok {
+				contentType = &ContentTypeStat{Type: content.Type}
+				types[content.Type] = contentType
+			}
+			contentType.Count++
+			contentType.Bytes += int64(len(content.Value))
+		}
+	}
+
+	for _, app := range apps {
+		stats.Applications = append(stats.Applications, *app)
+	}
+	slices.SortFunc(stats.Applications, func(a, b ApplicationStat) int {
+		return cmp.Or(cmp.Compare(b.Items, a.Items), cmp.Compare(a.Application, b.Application))
+	})
+	for _, contentType := range types {
+		stats.ContentTypes = append(stats.ContentTypes, *contentType)
+	}
+	slices.SortFunc(stats.ContentTypes, func(a, b ContentTypeStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Type, b.Type))
+	})
+
+	return &stats
+}
@@ -0,0 +1,435 @@
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrPinInUse is returned when a pin key is already assigned to another item
+var ErrPinInUse = errors.New("pin key is already used by another item")
+
+// ErrItemPinned is returned when merging would delete a pinned item
+var ErrItemPinned = errors.New("item is pinned")
+
+// PinKeys lists the pin keys Maccy accepts, in the order it assigns them.
+// a, q, v, w and z are reserved for select all, quit, paste, close and undo.
+var PinKeys = []string{
+	"b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l",
+	"m", "n", "o", "p", "r", "s", "t", "u", "x", "y",
+}
+
+// maxBatchSize keeps the number of bound parameters below SQLite's limit
+const maxBatchSize = 500
+
+// PinAction is the kind of change made by SyncPins
+type PinAction string
+
+const (
+	PinCreate PinAction = "create"
+	PinUpdate PinAction = "update"
+	PinUnpin  PinAction = "unpin"
+)
+
+// PinChange is one step of a pinned item synchronization
+type PinChange struct {
+	Action PinAction
+	// ItemID identifies the existing item for updates and unpins
+	ItemID int
+	Pin    string
+	Title  string
+	Text   string
+}
+
+// Merge describes duplicates to fold into one kept item
+type Merge struct {
+	Keep          int
+	Duplicates    []int
+	Copies        int
+	FirstCopiedAt time.Time
+	LastCopiedAt  time.Time
+}
+
+// DeleteItems deletes the given items and their contents in one transaction
+// and returns the number of deleted items
+func (r *Repository) DeleteItems(ids []int) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	columns, err := r.detectColumns()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted := 0
+	for start := 0; start < len(ids); start += maxBatchSize {
+		batch := ids[start:min(start+maxBatchSize, len(ids))]
+		n, err := deleteBatch(tx, columns, batch)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	r.log.Info("Deleted items", "table", columns.table, "count", deleted)
+	return deleted, nil
+}
+
+// SetPin assigns a pin key to an item, or removes its pin when key is empty
+func (r *Repository) SetPin(id int, key string) error {
+	columns, err := r.detectColumns()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setPin(tx, columns, id, key); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// SyncPins applies pinned item changes in one transaction. Unpins run first
+// so their keys can be reused by the other changes.
+func (r *Repository) SyncPins(changes []PinChange) error {
+	columns, err := r.detectColumns()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, action := range []PinAction{PinUnpin, PinUpdate, PinCreate} {
+		for _, change := range changes {
+			if change.Action != action {
+				continue
+			}
+
+			switch action {
+			case PinUnpin:
+				err = setPin(tx, columns, change.ItemID, "")
+			case PinUpdate:
+				err = updateText(tx, columns, change.ItemID, change.Title, change.Text)
+			case PinCreate:
+				var id int
+				id, err = insertTextItem(tx, columns, change.Title, change.Text)
+				if err == nil {
+					err = setPin(tx, columns, id, change.Pin)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("error applying %s of pin %q: %w", action, change.Pin, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// MergeItems folds duplicate items into the kept ones: each kept item
+// receives the merged copy count and copy time range and its duplicates are
+// deleted, all in one transaction. It fails with ErrItemPinned rather than
+// delete a pinned duplicate.
+func (r *Repository) MergeItems(merges []Merge) error {
+	columns, err := r.detectColumns()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	update := fmt.Sprintf("UPDATE %s SET %s = ?, %s = ?, %s = ?%s WHERE %s = ?",
+		columns.table, columns.numberOfCopies, columns.firstCopiedAt, columns.lastCopiedAt, columns.bumpVersion(), columns.id)
+
+	for _, merge := range merges {
+		result, err := tx.exec(update, merge.Copies,
+			timeToCocoaTimestamp(merge.FirstCopiedAt), timeToCocoaTimestamp(merge.LastCopiedAt), merge.Keep)
+		if err != nil {
+			return fmt.Errorf("error updating item %d: %w", merge.Keep, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %d", ErrItemNotFound, merge.Keep)
+		}
+
+		for start := 0; start < len(merge.Duplicates); start += maxBatchSize {
+			batch := merge.Duplicates[start:min(start+maxBatchSize, len(merge.Duplicates))]
+			if err := checkUnpinned(tx, columns, batch); err != nil {
+				return err
+			}
+			if _, err := deleteBatch(tx, columns, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	r.log.Info("Merged duplicate items", "table", columns.table, "groups", len(merges))
+	return nil
+}
+
+// UsedPins returns the pin keys currently assigned, mapped to their item IDs
+func (r *Repository) UsedPins() (map[string]int, error) {
+	items, err := r.GetPinnedItems()
+	if err != nil {
+		return nil, err
+	}
+
+	pins := make(map[string]int, len(items))
+	for _, item := range items {
+		pins[item.Pin] = item.ID
+	}
+	return pins, nil
+}
+
+func setPin(tx writeTx, c itemColumns, id int, key string) error {
+	var exists int
+	err := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", c.table, c.id), id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error looking up item: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("%w: %d", ErrItemNotFound, id)
+	}
+
+	var value interface{}
+	if key != "" {
+		var owner int
+		err := tx.QueryRow(fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s != ?",
+			c.id, c.table, c.pin, c.id), key, id).Scan(&owner)
+		if err == nil {
+			return fmt.Errorf("%w: %q is pinned to item %d", ErrPinInUse, key, owner)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error checking pin key: %w", err)
+		}
+		value = key
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET %s = ?%s WHERE %s = ?", c.table, c.pin, c.bumpVersion(), c.id)
+	if _, err := tx.exec(update, value, id); err != nil {
+		return fmt.Errorf("error updating pin: %w", err)
+	}
+	return nil
+}
+
+// updateText replaces the title and plain text content of an item
+func updateText(tx writeTx, c itemColumns, id int, title, text string) error {
+	update := fmt.Sprintf("UPDATE %s SET %s = ?%s WHERE %s = ?", c.table, c.title, c.bumpVersion(), c.id)
+	if _, err := tx.exec(update, title, id); err != nil {
+		return fmt.Errorf("error updating title: %w", err)
+	}
+
+	// Other representations such as HTML would no longer match the text
+	remove := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.contentTable, c.contentItem)
+	if _, err := tx.exec(remove, id); err != nil {
+		return fmt.Errorf("error removing contents: %w", err)
+	}
+	return insertContent(tx, c, id, ContentTypeText, []byte(text))
+}
+
+// insertTextItem creates a new history item holding plain text and returns its ID
+func insertTextItem(tx writeTx, c itemColumns, title, text string) (int, error) {
+	now := timeToCocoaTimestamp(time.Now())
+
+	var id int
+	if c.entity != "" {
+		entity, pk, err := nextPrimaryKey(tx, c.entity)
+		if err != nil {
+			return 0, err
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (%s, Z_ENT, %s, %s, %s, %s, %s) VALUES (?, ?, 1, ?, ?, ?, 1)",
+			c.table, c.id, c.version, c.title, c.firstCopiedAt, c.lastCopiedAt, c.numberOfCopies)
+		if _, err := tx.exec(insert, pk, entity, title, now, now); err != nil {
+			return 0, fmt.Errorf("error inserting item: %w", err)
+		}
+		id = pk
+	} else {
+		insert := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (?, ?, ?, 1)",
+			c.table, c.title, c.firstCopiedAt, c.lastCopiedAt, c.numberOfCopies)
+		result, err := tx.exec(insert, title, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting item: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = int(lastID)
+	}
+
+	if err := insertContent(tx, c, id, ContentTypeText, []byte(text)); err != nil {
+		return 0, err
+	}
+	tx.log.Debug("Inserted item", "table", c.table, "item_id", id)
+	return id, nil
+}
+
+func insertContent(tx writeTx, c itemColumns, itemID int, contentType string, value []byte) error {
+	if c.contentEntity != "" {
+		entity, pk, err := nextPrimaryKey(tx, c.contentEntity)
+		if err != nil {
+			return err
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (Z_PK, Z_ENT, Z_OPT, %s, %s, %s) VALUES (?, ?, 1, ?, ?, ?)",
+			c.contentTable, c.contentItem, c.contentType, c.contentValue)
+		_, err = tx.exec(insert, pk, entity, itemID, contentType, value)
+		if err != nil {
+			return fmt.Errorf("error inserting content: %w", err)
+		}
+		return nil
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)",
+		c.contentTable, c.contentItem, c.contentType, c.contentValue)
+	if _, err := tx.exec(insert, itemID, contentType, value); err != nil {
+		return fmt.Errorf("error inserting content: %w", err)
+	}
+	return nil
+}
+
+// nextPrimaryKey reserves the next Core Data primary key of an entity by
+// bumping its counter in Z_PRIMARYKEY, returning the entity number and key
+func nextPrimaryKey(tx writeTx, entity string) (int, int, error) {
+	var ent, max int
+	err := tx.QueryRow("SELECT Z_ENT, Z_MAX FROM Z_PRIMARYKEY WHERE Z_NAME = ?", entity).Scan(&ent, &max)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading primary key counter for %s: %w", entity, err)
+	}
+
+	if _, err := tx.exec("UPDATE Z_PRIMARYKEY SET Z_MAX = ? WHERE Z_ENT = ?", max+1, ent); err != nil {
+		return 0, 0, fmt.Errorf("error updating primary key counter for %s: %w", entity, err)
+	}
+	return ent, max + 1, nil
+}
+
+// bumpVersion returns the SET fragment incrementing the Core Data optimistic
+// locking column, so Core Data notices the row changed underneath it
+func (c itemColumns) bumpVersion() string {
+	if c.version == "" {
+		return ""
+	}
+	return fmt.Sprintf(", %s = COALESCE(%s, 0) + 1", c.version, c.version)
+}
+
+// checkUnpinned fails with ErrItemPinned if any of the items has a pin
+func checkUnpinned(tx writeTx, c itemColumns, ids []int) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	var id int
+	var pin string
+	err := tx.QueryRow(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s) AND %s IS NOT NULL AND %s != '' LIMIT 1",
+		c.id, c.pin, c.table, c.id, placeholders, c.pin, c.pin), args...).Scan(&id, &pin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error checking pins: %w", err)
+	}
+	return fmt.Errorf("%w: item %d has pin %q", ErrItemPinned, id, pin)
+}
+
+func deleteBatch(tx writeTx, c itemColumns, ids []int) (int, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	contentQuery := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", c.contentTable, c.contentItem, placeholders)
+	if _, err := tx.exec(contentQuery, args...); err != nil {
+		return 0, fmt.Errorf("error deleting contents: %w", err)
+	}
+
+	itemQuery := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", c.table, c.id, placeholders)
+	result, err := tx.exec(itemQuery, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting items: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// writeTx is a write transaction that traces its statements
+type writeTx struct {
+	*sql.Tx
+	log *slog.Logger
+}
+
+// begin starts a write transaction logging to the repository's logger
+func (r *Repository) begin() (writeTx, error) {
+	tx, err := r.db.Begin()
+	return writeTx{Tx: tx, log: r.log}, err
+}
+
+// exec runs a statement in the transaction, tracing it with its arguments
+// and duration
+func (tx writeTx) exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := tx.Exec(query, args...)
+	traceQuery(tx.log, query, args, start, err)
+	return result, err
+}
+
+// detectColumns returns the column mapping of the schema flavor present in
+// the database. Writes must not fall back from one flavor to the other
+// halfway through, so the flavor is determined up front.
+func (r *Repository) detectColumns() (itemColumns, error) {
+	for _, columns := range []itemColumns{standardColumns, alternativeColumns} {
+		var count int
+		err := r.queryRow(`
+			SELECT COUNT(*) FROM sqlite_master
+			WHERE type='table' AND name IN (?, ?)
+		`, columns.table, columns.contentTable).Scan(&count)
+		if err != nil {
+			return itemColumns{}, fmt.Errorf("error inspecting schema: %w", err)
+		}
+		if count == 2 {
+			return columns, nil
+		}
+	}
+	return itemColumns{}, fmt.Errorf("no supported history tables found in database")
+}
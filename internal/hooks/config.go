@@ -0,0 +1,103 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultConcurrency = 4
+)
+
+// Config describes the hooks to run for new clipboard items
+type Config struct {
+	// Concurrency limits how many hook commands run at the same time
+	Concurrency int `json:"concurrency,omitempty"`
+	// DeadLetterFile receives a JSON line for every failed hook execution
+	DeadLetterFile string `json:"deadLetterFile,omitempty"`
+	Hooks          []Hook `json:"hooks"`
+}
+
+// Hook runs a local command for clipboard items matching its criteria
+type Hook struct {
+	Name  string `json:"name"`
+	Match Match  `json:"match"`
+	// Command is the program and its arguments; the item is passed as JSON on stdin
+	Command []string `json:"command"`
+	// Timeout is a Go duration string such as "10s"
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// Match holds the criteria an item must satisfy for a hook to run.
+// Empty criteria match every item.
+type Match struct {
+	Application string `json:"application,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	// Pattern is a regular expression applied to the item's text content
+	Pattern string `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// DefaultConfigPath returns the default location of the hooks configuration
+func DefaultConfigPath() string {
+	usr, err := user.Current()
+	if err != nil {
+		return "hooks.json"
+	}
+	return filepath.Join(usr.HomeDir, ".config", "sunlitsparrow", "hooks.json")
+}
+
+// LoadConfig reads and validates a hooks configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading hooks config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing hooks config: %w", err)
+	}
+
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+
+	for i := range cfg.Hooks {
+		hook := &cfg.Hooks[i]
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if len(hook.Command) == 0 {
+			return nil, fmt.Errorf("hook %q has no command", hook.Name)
+		}
+
+		hook.timeout = defaultTimeout
+		if hook.Timeout != "" {
+			timeout, err := time.ParseDuration(hook.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("hook %q has invalid timeout: %w", hook.Name, err)
+			}
+			hook.timeout = timeout
+		}
+
+		if hook.Match.Pattern != "" {
+			pattern, err := regexp.Compile(hook.Match.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("hook %q has invalid pattern: %w", hook.Name, err)
+			}
+			hook.Match.pattern = pattern
+		}
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,172 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+)
+
+// waitDelay bounds how long a killed hook may keep its output pipes open
+const waitDelay = 2 * time.Second
+
+// Runner executes configured hooks for clipboard items
+type Runner struct {
+	config *Config
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// deadLetter is the record written for a failed hook execution
+type deadLetter struct {
+	Time   time.Time `json:"time"`
+	Hook   string    `json:"hook"`
+	ItemID int       `json:"itemId"`
+	Error  string    `json:"error"`
+	Stderr string    `json:"stderr,omitempty"`
+}
+
+// NewRunner creates a new hook runner
+func NewRunner(config *Config) *Runner {
+	return &Runner{
+		config: config,
+		slots:  make(chan struct{}, config.Concurrency),
+	}
+}
+
+// Dispatch starts every hook matching the item. It blocks only while all
+// concurrency slots are in use.
+func (r *Runner) Dispatch(ctx context.Context, item history.HistoryItem) {
+	for i := range r.config.Hooks {
+		hook := &r.config.Hooks[i]
+		if !hook.Match.matches(item) {
+			continue
+		}
+
+		select {
+		case r.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer func() { <-r.slots }()
+			r.run(ctx, hook, item)
+		}()
+	}
+}
+
+// Wait blocks until all running hooks have finished
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) run(ctx context.Context, hook *Hook, item history.HistoryItem) {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		r.recordFailure(ctx, hook, item, fmt.Errorf("error encoding item: %w", err), "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hook.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	command := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	command.Stdin = bytes.NewReader(payload)
+	command.Stdout = os.Stdout
+	command.Stderr = &stderr
+	// Without WaitDelay a grandchild holding stderr open keeps Run blocked
+	// long after the hook itself was killed
+	command.WaitDelay = waitDelay
+
+	log := logger.FromContext(ctx).With("hook", hook.Name, "item_id", item.ID)
+	log.Debug("Running hook")
+	start := time.Now()
+	err = command.Run()
+	if err != nil {
+		switch ctx.Err() {
+		case context.Canceled:
+			// The watcher is shutting down; this is not a hook failure
+			log.Debug("Hook cancelled", "error", err)
+			return
+		case context.DeadlineExceeded:
+			err = fmt.Errorf("timed out after %s", hook.timeout)
+		}
+		r.recordFailure(ctx, hook, item, err, stderr.String())
+		return
+	}
+	log.Debug("Hook finished", "duration", time.Since(start))
+}
+
+// recordFailure logs a failed hook and appends it to the dead-letter file
+func (r *Runner) recordFailure(ctx context.Context, hook *Hook, item history.HistoryItem, err error, stderr string) {
+	log := logger.FromContext(ctx).With("hook", hook.Name, "item_id", item.ID)
+	log.Info("Hook failed", "error", err)
+
+	if r.config.DeadLetterFile == "" {
+		return
+	}
+
+	record, marshalErr := json.Marshal(deadLetter{
+		Time:   time.Now(),
+		Hook:   hook.Name,
+		ItemID: item.ID,
+		Error:  err.Error(),
+		Stderr: stderr,
+	})
+	if marshalErr != nil {
+		log.Debug("Error encoding dead-letter record", "error", marshalErr)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, openErr := os.OpenFile(r.config.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if openErr != nil {
+		log.Info("Error opening dead-letter file", "file", r.config.DeadLetterFile, "error", openErr)
+		return
+	}
+	defer file.Close()
+
+	if _, writeErr := file.Write(append(record, '\n')); writeErr != nil {
+		log.Info("Error writing dead-letter record", "file", r.config.DeadLetterFile, "error", writeErr)
+	}
+}
+
+// matches reports whether the item satisfies all criteria of the match
+func (m Match) matches(item history.HistoryItem) bool {
+	if m.Application != "" && m.Application != item.Application {
+		return false
+	}
+
+	if m.ContentType != "" {
+		found := false
+		for _, content := range item.Contents {
+			if content.Type == m.ContentType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if m.pattern != nil && !m.pattern.MatchString(item.Text()) {
+		return false
+	}
+
+	return true
+}
@@ -1,58 +1,146 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
 )
 
-var (
-	InfoLogger  *log.Logger
-	DebugLogger *log.Logger
-	TraceLogger *log.Logger
-	nullWriter  = io.Discard
+// LevelTrace is below slog.LevelDebug and logs every executed query
+const LevelTrace = slog.Level(-8)
+
+// Format selects how log records are written
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
 )
 
+// ParseFormat validates a log format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q (expected text or json)", name)
+}
+
+// Options configures the default logger
+type Options struct {
+	// Verbosity is the number of -v flags: 1 logs info, 2 debug and 3 trace
+	// records. Nothing is logged at 0 unless File is set, which logs info.
+	Verbosity int
+	Format    Format
+	// File receives the records instead of stderr when set
+	File string
+}
+
+var current atomic.Pointer[slog.Logger]
+
 func init() {
-	// Set up loggers with prefixes
-	InfoLogger = log.New(nullWriter, "INFO: ", log.Ltime)
-	DebugLogger = log.New(nullWriter, "DEBUG: ", log.Ltime)
-	TraceLogger = log.New(nullWriter, "TRACE: ", log.Ltime)
+	current.Store(slog.New(slog.DiscardHandler))
+}
+
+// Setup replaces the default logger according to opts. The returned closer
+// releases the log file, if any.
+func Setup(opts Options) (io.Closer, error) {
+	verbosity := opts.Verbosity
+	if verbosity == 0 && opts.File != "" {
+		verbosity = 1
+	}
+	if verbosity == 0 {
+		current.Store(slog.New(slog.DiscardHandler))
+		return nopCloser{}, nil
+	}
+
+	var w io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("error opening log file: %w", err)
+		}
+		w, closer = f, f
+	}
 
-	// Default to silent
-	SetLogLevel(0)
+	handlerOpts := &slog.HandlerOptions{
+		Level:       levelFor(verbosity),
+		ReplaceAttr: replaceLevel,
+	}
+	var handler slog.Handler
+	if opts.Format == FormatJSON {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	}
+	current.Store(slog.New(handler))
+	return closer, nil
 }
 
-// SetLogLevel sets the logging level based on verbosity
-func SetLogLevel(verbosity int) {
-	// Disable all loggers by default
-	InfoLogger.SetOutput(nullWriter)
-	DebugLogger.SetOutput(nullWriter)
-	TraceLogger.SetOutput(nullWriter)
+// nopCloser is returned by Setup when there is no log file to close
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
 
-	// Enable loggers based on verbosity
-	if verbosity >= 1 {
-		InfoLogger.SetOutput(os.Stderr)
+// levelFor maps the number of -v flags to the lowest level logged
+func levelFor(verbosity int) slog.Level {
+	switch {
+	case verbosity >= 3:
+		return LevelTrace
+	case verbosity == 2:
+		return slog.LevelDebug
 	}
-	if verbosity >= 2 {
-		DebugLogger.SetOutput(os.Stderr)
+	return slog.LevelInfo
+}
+
+// replaceLevel names the trace level, which slog would print as DEBUG-4
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
 	}
-	if verbosity >= 3 {
-		TraceLogger.SetOutput(os.Stderr)
+	return a
+}
+
+// Default returns the logger configured by Setup
+func Default() *slog.Logger {
+	return current.Load()
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
 	}
+	return Default()
 }
 
-// Info logs an info message if the log level is appropriate
-func Info(format string, v ...interface{}) {
-	InfoLogger.Printf(format, v...)
+// Info logs a message with key-value attributes on the default logger
+func Info(msg string, args ...any) {
+	Default().Info(msg, args...)
 }
 
-// Debug logs a debug message if the log level is appropriate
-func Debug(format string, v ...interface{}) {
-	DebugLogger.Printf(format, v...)
+// Debug logs a message with key-value attributes on the default logger
+func Debug(msg string, args ...any) {
+	Default().Debug(msg, args...)
 }
 
-// Trace logs a trace message if the log level is appropriate
-func Trace(format string, v ...interface{}) {
-	TraceLogger.Printf(format, v...)
+// Trace logs a message with key-value attributes on the default logger
+func Trace(msg string, args ...any) {
+	Default().Log(context.Background(), LevelTrace, msg, args...)
 }
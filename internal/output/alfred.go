@@ -0,0 +1,132 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+)
+
+// alfredResult is the top-level Script Filter JSON document, also understood
+// by Raycast script commands
+type alfredResult struct {
+	Items []alfredItem `json:"items"`
+}
+
+type alfredItem struct {
+	UID          string      `json:"uid"`
+	Title        string      `json:"title"`
+	Subtitle     string      `json:"subtitle"`
+	Arg          string      `json:"arg"`
+	Match        string      `json:"match,omitempty"`
+	QuickLookURL string      `json:"quicklookurl,omitempty"`
+	Icon         *alfredIcon `json:"icon,omitempty"`
+	Text         *alfredText `json:"text,omitempty"`
+}
+
+type alfredIcon struct {
+	Path string `json:"path"`
+}
+
+type alfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+// imageExtensions maps image pasteboard types to file extensions
+var imageExtensions = map[string]string{
+	history.ContentTypePNG:  "png",
+	history.ContentTypeTIFF: "tiff",
+}
+
+// WriteAlfred writes the items as an Alfred Script Filter JSON document.
+// Image contents are cached on disk so Alfred can show them as icon and
+// Quick Look preview.
+func WriteAlfred(w io.Writer, items []history.HistoryItem) error {
+	result := alfredResult{Items: make([]alfredItem, 0, len(items))}
+
+	for _, item := range items {
+		title := strings.Join(strings.Fields(item.Title), " ")
+		if title == "" {
+			title = fmt.Sprintf("Item %d", item.ID)
+		}
+
+		text := item.DecodedText()
+		entry := alfredItem{
+			UID:      strconv.Itoa(item.ID),
+			Title:    title,
+			Subtitle: alfredSubtitle(item),
+			Arg:      strconv.Itoa(item.ID),
+			Match:    title + " " + item.Application,
+		}
+		if text != "" {
+			entry.Text = &alfredText{Copy: text, LargeType: text}
+		}
+
+		if path := cacheImage(item); path != "" {
+			entry.Icon = &alfredIcon{Path: path}
+			entry.QuickLookURL = path
+		}
+
+		result.Items = append(result.Items, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func alfredSubtitle(item history.HistoryItem) string {
+	parts := []string{}
+	if item.Pin != "" {
+		parts = append(parts, "⌘"+item.Pin)
+	}
+	app := item.Application
+	if app == "" {
+		app = "<unknown>"
+	}
+	parts = append(parts, app)
+	if !item.LastCopiedAt.IsZero() {
+		parts = append(parts, item.LastCopiedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if item.NumberOfCopies > 1 {
+		parts = append(parts, fmt.Sprintf("%d copies", item.NumberOfCopies))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// cacheImage writes the first image content of the item to the user cache
+// directory and returns its path, or an empty string if there is none
+func cacheImage(item history.HistoryItem) string {
+	for _, content := range item.Contents {
+		ext, ok := imageExtensions[content.Type]
+		if !ok {
+			continue
+		}
+
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			logger.Debug("Error locating cache directory", "error", err)
+			return ""
+		}
+		dir := filepath.Join(cacheDir, "sunlitsparrow", "images")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			logger.Debug("Error creating image cache", "error", err)
+			return ""
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%d.%s", item.ID, ext))
+		if err := os.WriteFile(path, content.Value, 0o600); err != nil {
+			logger.Debug("Error caching image", "item_id", item.ID, "error", err)
+			return ""
+		}
+		return path
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package output
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"gopkg.in/yaml.v3"
+)
+
+// BinaryMode selects how non-text contents appear in JSON and YAML output
+type BinaryMode string
+
+const (
+	// BinaryBase64 writes the raw bytes base64-encoded
+	BinaryBase64 BinaryMode = "base64"
+	// BinarySummary replaces the bytes with their size and a description
+	BinarySummary BinaryMode = "summary"
+)
+
+// ParseBinaryMode validates a binary mode name. An empty name selects the
+// default of the format: base64 for JSON, which round-trips through
+// --from, and a summary for the formats meant for reading.
+func ParseBinaryMode(name string, format Format) (BinaryMode, error) {
+	switch BinaryMode(name) {
+	case BinaryBase64, BinarySummary:
+		return BinaryMode(name), nil
+	case "":
+		if format == FormatYAML {
+			return BinarySummary, nil
+		}
+		return BinaryBase64, nil
+	}
+	return "", fmt.Errorf("unknown binary mode %q (expected base64 or summary)", name)
+}
+
+// Item is the structured form of a history item written by the JSON and
+// YAML encoders. Its JSON form matches history.HistoryItem.
+type Item struct {
+	ID             int       `json:"id" yaml:"id"`
+	Title          string    `json:"title" yaml:"title"`
+	Pin            string    `json:"pin,omitempty" yaml:"pin,omitempty"`
+	FirstCopiedAt  time.Time `json:"firstCopiedAt" yaml:"firstCopiedAt"`
+	LastCopiedAt   time.Time `json:"lastCopiedAt" yaml:"lastCopiedAt"`
+	NumberOfCopies int       `json:"numberOfCopies" yaml:"numberOfCopies"`
+	Application    string    `json:"application,omitempty" yaml:"application,omitempty"`
+	Contents       []Content `json:"contents,omitempty" yaml:"contents,omitempty"`
+}
+
+// Content is a content of an item. Text is written as is and binary data
+// base64-encoded in Value, or described by Size and Summary.
+type Content struct {
+	Type    string  `json:"type" yaml:"type"`
+	Value   *string `json:"value,omitempty" yaml:"value,omitempty"`
+	Size    int     `json:"size,omitempty" yaml:"size,omitempty"`
+	Summary string  `json:"summary,omitempty" yaml:"summary,omitempty"`
+}
+
+// summaries describes well-known binary pasteboard types
+var summaries = map[string]string{
+	history.ContentTypePNG:  "PNG image",
+	history.ContentTypeTIFF: "TIFF image",
+}
+
+// NewItem converts a history item to its structured form
+func NewItem(item history.HistoryItem, binary BinaryMode) Item {
+	return Item{
+		ID:             item.ID,
+		Title:          item.Title,
+		Pin:            item.Pin,
+		FirstCopiedAt:  item.FirstCopiedAt,
+		LastCopiedAt:   item.LastCopiedAt,
+		NumberOfCopies: item.NumberOfCopies,
+		Application:    item.Application,
+		Contents:       newContents(item.Contents, binary),
+	}
+}
+
+func newContents(contents []history.Content, binary BinaryMode) []Content {
+	if contents == nil {
+		return nil
+	}
+
+	result := make([]Content, len(contents))
+	for i, content := range contents {
+		result[i] = newContent(content, binary)
+	}
+	return result
+}
+
+// newContent writes plain text as is. Other types are base64-encoded, as in
+// history.HistoryItem's JSON, unless summarizing: then any content holding
+// text, such as HTML or RTF, is written as is and only binary data is
+// summarized.
+func newContent(content history.Content, binary BinaryMode) Content {
+	if content.Type == history.ContentTypeText ||
+		(binary == BinarySummary && content.IsText() && utf8.Valid(content.Value)) {
+		text := string(content.Value)
+		return Content{Type: content.Type, Value: &text}
+	}
+
+	if binary == BinarySummary {
+		summary, ok := summaries[content.Type]
+		if !ok {
+			summary = "binary data"
+		}
+		return Content{Type: content.Type, Size: len(content.Value), Summary: summary + ", " + HumanBytes(len(content.Value))}
+	}
+	encoded := base64.StdEncoding.EncodeToString(content.Value)
+	return Content{Type: content.Type, Value: &encoded}
+}
+
+// Encode writes v as indented JSON or YAML
+func Encode(w io.Writer, format Format, v interface{}) error {
+	switch format {
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Errorf("error formatting YAML: %w", err)
+		}
+		return encoder.Close()
+	}
+
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error formatting JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+// EncodeItems writes items as JSON or YAML, limited to the given fields
+// unless fields is nil
+func EncodeItems(w io.Writer, format Format, items []history.HistoryItem, fields []Field, binary BinaryMode) error {
+	return Encode(w, format, ItemValues(items, fields, binary))
+}
+
+// ItemValues converts items to their structured form for Encode, limited to
+// the given fields unless fields is nil
+func ItemValues(items []history.HistoryItem, fields []Field, binary BinaryMode) interface{} {
+	if fields != nil {
+		records := make([]record, len(items))
+		for i, item := range items {
+			records[i] = newRecord(item, fields, binary)
+		}
+		return records
+	}
+
+	documents := make([]Item, len(items))
+	for i, item := range items {
+		documents[i] = NewItem(item, binary)
+	}
+	return documents
+}
@@ -0,0 +1,236 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+	"gopkg.in/yaml.v3"
+)
+
+// Field is an item attribute that can be selected for JSON and table output
+type Field struct {
+	// Name is the JSON key and the name accepted by ParseFields
+	Name string
+	// column describes the field's table column
+	column table.Column
+	// value returns the value written to JSON and YAML output
+	value func(item history.HistoryItem, binary BinaryMode) interface{}
+	// cell returns the value shown in a table
+	cell func(item history.HistoryItem) string
+}
+
+// Fields lists every selectable field in its default order
+var Fields = []Field{
+	{Name: "id", column: table.Column{Header: "ID", Align: table.AlignRight},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.ID },
+		cell:  func(item history.HistoryItem) string { return strconv.Itoa(item.ID) }},
+	{Name: "title", column: table.Column{Header: "Title", Max: 60, Flex: true},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.Title },
+		cell:  func(item history.HistoryItem) string { return item.Title }},
+	{Name: "pin", column: table.Column{Header: "Pin"},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.Pin },
+		cell:  func(item history.HistoryItem) string { return orDefault(item.Pin, "-") }},
+	{Name: "firstCopiedAt", column: table.Column{Header: "First Copied"},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.FirstCopiedAt },
+		cell:  func(item history.HistoryItem) string { return history.FormatTime(item.FirstCopiedAt) }},
+	{Name: "lastCopiedAt", column: table.Column{Header: "Last Copied"},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.LastCopiedAt },
+		cell:  func(item history.HistoryItem) string { return history.FormatTime(item.LastCopiedAt) }},
+	{Name: "numberOfCopies", column: table.Column{Header: "Count", Align: table.AlignRight},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.NumberOfCopies },
+		cell:  func(item history.HistoryItem) string { return strconv.Itoa(item.NumberOfCopies) }},
+	{Name: "application", column: table.Column{Header: "Application", Max: 40, Flex: true},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.Application },
+		cell:  func(item history.HistoryItem) string { return orDefault(item.Application, "<unknown>") }},
+	{Name: "text", column: table.Column{Header: "Text", Max: 60, Flex: true},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return item.DecodedText() },
+		cell:  func(item history.HistoryItem) string { return item.DecodedText() }},
+	{Name: "types", column: table.Column{Header: "Types", Max: 40, Flex: true},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return contentTypes(item) },
+		cell:  func(item history.HistoryItem) string { return strings.Join(contentTypes(item), ",") }},
+	{Name: "size", column: table.Column{Header: "Size", Align: table.AlignRight},
+		value: func(item history.HistoryItem, _ BinaryMode) interface{} { return itemSize(item) },
+		cell:  func(item history.HistoryItem) string { return HumanBytes(itemSize(item)) }},
+	{Name: "contents", column: table.Column{Header: "Contents", Align: table.AlignRight},
+		value: func(item history.HistoryItem, binary BinaryMode) interface{} {
+			if item.Contents == nil {
+				return []interface{}{}
+			}
+			return newContents(item.Contents, binary)
+		},
+		cell: func(item history.HistoryItem) string { return strconv.Itoa(len(item.Contents)) }},
+}
+
+// DefaultTableFields are the columns of the item table without --fields
+var DefaultTableFields = []string{"id", "title", "pin", "firstCopiedAt", "lastCopiedAt", "numberOfCopies", "application"}
+
+// fieldAliases are short names accepted by ParseFields
+var fieldAliases = map[string]string{
+	"first":  "firstCopiedAt",
+	"last":   "lastCopiedAt",
+	"copies": "numberOfCopies",
+	"count":  "numberOfCopies",
+	"app":    "application",
+}
+
+// FieldNames returns the selectable field names for use in flag help
+func FieldNames() string {
+	names := make([]string, len(Fields))
+	for i, field := range Fields {
+		names[i] = field.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseFields parses a comma-separated list of field names, keeping the
+// given order
+func ParseFields(spec string) ([]Field, error) {
+	var fields []Field
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if alias, ok := fieldAliases[strings.ToLower(name)]; ok {
+			name = alias
+		}
+
+		found := false
+		for _, field := range Fields {
+			if strings.EqualFold(name, field.Name) {
+				fields = append(fields, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q (expected one of %s)", name, FieldNames())
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected (expected one or more of %s)", FieldNames())
+	}
+	return fields, nil
+}
+
+// record is an item reduced to the selected fields, which keeps the
+// fields in order when encoded as JSON or YAML
+type record struct {
+	fields []Field
+	values []interface{}
+}
+
+func newRecord(item history.HistoryItem, fields []Field, binary BinaryMode) record {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = field.value(item, binary)
+	}
+	return record{fields: fields, values: values}
+}
+
+// MarshalJSON implements json.Marshaler
+func (r record) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range r.fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Name)
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (r record) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i, field := range r.fields {
+		var value yaml.Node
+		if err := value.Encode(r.values[i]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.Name}, &value)
+	}
+	return node, nil
+}
+
+// WriteItemsTable writes the items as a table of the default fields
+func WriteItemsTable(w io.Writer, items []history.HistoryItem) error {
+	fields, _ := ParseFields(strings.Join(DefaultTableFields, ","))
+	return WriteFieldsTable(w, items, fields)
+}
+
+// WriteFieldsTable writes the selected fields of the items as a table
+func WriteFieldsTable(w io.Writer, items []history.HistoryItem, fields []Field) error {
+	columns := make([]table.Column, len(fields))
+	for i, field := range fields {
+		columns[i] = field.column
+	}
+
+	t := table.New(columns...)
+	for _, item := range items {
+		cells := make([]string, len(fields))
+		for i, field := range fields {
+			cells[i] = field.cell(item)
+		}
+		t.Append(cells...)
+	}
+	return t.Render(w)
+}
+
+func contentTypes(item history.HistoryItem) []string {
+	types := make([]string, len(item.Contents))
+	for i, content := range item.Contents {
+		types[i] = content.Type
+	}
+	return types
+}
+
+func itemSize(item history.HistoryItem) int {
+	size := 0
+	for _, content := range item.Contents {
+		size += len(content.Value)
+	}
+	return size
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// singleLine collapses whitespace, including newlines, into single spaces
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncate shortens s to at most n runes, marking the cut with "..." when
+// n leaves room for it
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	switch {
+	case len(runes) <= n:
+		return s
+	case n <= 0:
+		return ""
+	case n < 4:
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,45 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format identifies how listing commands render history items
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatTable  Format = "table"
+	FormatFZF    Format = "fzf"
+	FormatAlfred Format = "alfred"
+	FormatYAML   Format = "yaml"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatJSON, FormatYAML, FormatTable, FormatFZF, FormatAlfred}
+
+// IsStructured reports whether the format encodes items as a document:
+// JSON or YAML
+func (f Format) IsStructured() bool {
+	return f == FormatJSON || f == FormatYAML
+}
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (expected one of %s)", name, FormatNames())
+}
+
+// FormatNames returns the supported format names for use in flag help
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,44 @@
+package output
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// maxPreviewRunes bounds the preview text emitted per item
+const maxPreviewRunes = 500
+
+// WriteFZF writes one NUL-terminated "<id>\t<preview>" record per item,
+// meant for fzf --read0 --delimiter '\t' --with-nth 2..
+func WriteFZF(w io.Writer, items []history.HistoryItem) error {
+	bw := bufio.NewWriter(w)
+
+	for _, item := range items {
+		bw.WriteString(strconv.Itoa(item.ID))
+		bw.WriteByte('\t')
+		bw.WriteString(previewText(item))
+		bw.WriteByte(0)
+	}
+
+	return bw.Flush()
+}
+
+// previewText returns the item's text, falling back to its title, without
+// NUL bytes and truncated to maxPreviewRunes
+func previewText(item history.HistoryItem) string {
+	text := item.DecodedText()
+	if strings.TrimSpace(text) == "" {
+		text = item.Title
+	}
+	text = strings.ReplaceAll(text, "\x00", "")
+
+	runes := []rune(text)
+	if len(runes) > maxPreviewRunes {
+		text = string(runes[:maxPreviewRunes-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// templateFuncs are the helper functions available in item templates
+var templateFuncs = template.FuncMap{
+	// truncate shortens a string to at most n characters
+	"truncate": func(n int, s string) string { return truncate(s, n) },
+	// ago renders the time elapsed since t, e.g. "3h ago"
+	"ago": func(t time.Time) string { return Ago(t, time.Now()) },
+	// humanBytes renders a byte count with binary units, e.g. "1.5 KiB"
+	"humanBytes": func(n int) string { return HumanBytes(n) },
+	// text returns the decoded text of an item
+	"text": func(item history.HistoryItem) string { return item.DecodedText() },
+	// size returns the combined size of an item's contents in bytes
+	"size": func(item history.HistoryItem) int { return itemSize(item) },
+	// oneline collapses whitespace, including newlines, into single spaces
+	"oneline": singleLine,
+}
+
+// ParseTemplate compiles a Go text/template rendered once per item, with
+// the item as its data
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("item").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// WriteTemplate renders the template for every item, each followed by a
+// newline
+func WriteTemplate(w io.Writer, items []history.HistoryItem, tmpl *template.Template) error {
+	for _, item := range items {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("error rendering item %d: %w", item.ID, err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ago renders the time elapsed between t and now in its largest unit
+func Ago(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d/(30*24*time.Hour)))
+	}
+	return fmt.Sprintf("%dy ago", int(d/(365*24*time.Hour)))
+}
+
+// HumanBytes renders a byte count with binary units
+func HumanBytes(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		value /= 1024
+		if value < 1024 || unit == "GiB" {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return ""
+}
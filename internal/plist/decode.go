@@ -0,0 +1,332 @@
+// Package plist decodes Apple binary property lists (bplist00).
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// UID is a reference used by keyed archives such as NSKeyedArchiver output
+type UID uint64
+
+// ErrNotBinary is returned for data that is not a binary property list
+var ErrNotBinary = errors.New("not a binary property list")
+
+const (
+	magic       = "bplist00"
+	trailerSize = 32
+	// maxDepth bounds nesting so malformed data cannot exhaust the stack
+	maxDepth = 512
+	// maxDecoded bounds the number of decoded values, as shared references
+	// can make the decoded tree exponentially larger than the data
+	maxDecoded = 1 << 20
+)
+
+// cocoaEpoch is the reference date of plist dates
+var cocoaEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsBinary reports whether data starts with the binary property list header
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Decode decodes a binary property list into Go values: nil, bool, int64 (or
+// uint64 for values that do not fit), float64, time.Time, []byte, string,
+// UID, []interface{} for arrays and sets, and map[string]interface{} for
+// dictionaries, whose non-string keys are formatted with fmt.Sprint.
+func Decode(data []byte) (interface{}, error) {
+	if !IsBinary(data) {
+		return nil, ErrNotBinary
+	}
+	if len(data) < len(magic)+trailerSize {
+		return nil, errors.New("binary property list is truncated")
+	}
+
+	trailer := data[len(data)-trailerSize:]
+	d := &decoder{
+		data:        data,
+		offsetSize:  int(trailer[6]),
+		refSize:     int(trailer[7]),
+		numObjects:  binary.BigEndian.Uint64(trailer[8:16]),
+		topObject:   binary.BigEndian.Uint64(trailer[16:24]),
+		tableOffset: binary.BigEndian.Uint64(trailer[24:32]),
+		visiting:    map[uint64]bool{},
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+
+	return d.object(d.topObject, 0)
+}
+
+type decoder struct {
+	data        []byte
+	offsetSize  int
+	refSize     int
+	numObjects  uint64
+	topObject   uint64
+	tableOffset uint64
+	// visiting holds the collections being decoded, to detect cycles
+	visiting map[uint64]bool
+	decoded  int
+}
+
+func (d *decoder) validate() error {
+	if d.offsetSize < 1 || d.offsetSize > 8 || d.refSize < 1 || d.refSize > 8 {
+		return errors.New("invalid binary property list trailer")
+	}
+	tableEnd := uint64(len(d.data) - trailerSize)
+	if d.numObjects == 0 || d.topObject >= d.numObjects || d.tableOffset < uint64(len(magic)) ||
+		d.tableOffset > tableEnd || d.numObjects > (tableEnd-d.tableOffset)/uint64(d.offsetSize) {
+		return errors.New("invalid binary property list offset table")
+	}
+	return nil
+}
+
+// offset returns the position of an object from the offset table
+func (d *decoder) offset(ref uint64) (uint64, error) {
+	if ref >= d.numObjects {
+		return 0, fmt.Errorf("object reference %d out of range", ref)
+	}
+	start := d.tableOffset + ref*uint64(d.offsetSize)
+	offset := readUint(d.data[start : start+uint64(d.offsetSize)])
+	if offset < uint64(len(magic)) || offset >= d.tableOffset {
+		return 0, fmt.Errorf("object offset %d out of range", offset)
+	}
+	return offset, nil
+}
+
+// bytes returns n bytes at offset, checking bounds
+func (d *decoder) bytes(offset, n uint64) ([]byte, error) {
+	if n > d.tableOffset || offset > d.tableOffset-n {
+		return nil, fmt.Errorf("object at %d extends past the object table", offset)
+	}
+	return d.data[offset : offset+n], nil
+}
+
+func (d *decoder) object(ref uint64, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("binary property list is nested too deeply")
+	}
+	if d.decoded++; d.decoded > maxDecoded {
+		return nil, errors.New("binary property list has too many values")
+	}
+
+	offset, err := d.offset(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	marker := d.data[offset]
+	kind, info := marker>>4, marker&0x0f
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x0:
+			return nil, nil
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		}
+		return nil, fmt.Errorf("unknown simple value 0x%02x", marker)
+
+	case 0x1:
+		b, err := d.bytes(offset+1, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		return decodeInt(b)
+
+	case 0x2:
+		b, err := d.bytes(offset+1, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, fmt.Errorf("invalid real of %d bytes", len(b))
+
+	case 0x3:
+		b, err := d.bytes(offset+1, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return cocoaEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+
+	case 0x4, 0x5, 0x6:
+		count, start, err := d.count(offset, info)
+		if err != nil {
+			return nil, err
+		}
+		// Check the length against the object table before it is doubled or
+		// used to allocate, so a forged count cannot overflow
+		if count > d.tableOffset || (kind == 0x6 && count > d.tableOffset/2) {
+			return nil, fmt.Errorf("object at %d extends past the object table", offset)
+		}
+		size := count
+		if kind == 0x6 {
+			size *= 2
+		}
+		b, err := d.bytes(start, size)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case 0x4:
+			return append([]byte(nil), b...), nil
+		case 0x5:
+			return string(b), nil
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+
+	case 0x8:
+		b, err := d.bytes(offset+1, uint64(info)+1)
+		if err != nil {
+			return nil, err
+		}
+		return UID(readUint(b)), nil
+
+	case 0xa, 0xc:
+		refs, err := d.refs(offset, info, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.enter(ref); err != nil {
+			return nil, err
+		}
+		defer delete(d.visiting, ref)
+		values := make([]interface{}, len(refs))
+		for i, r := range refs {
+			if values[i], err = d.object(r, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case 0xd:
+		refs, err := d.refs(offset, info, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.enter(ref); err != nil {
+			return nil, err
+		}
+		defer delete(d.visiting, ref)
+		n := len(refs) / 2
+		dict := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := d.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.object(refs[n+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			dict[name] = value
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("unknown object type 0x%02x", marker)
+}
+
+// enter marks a collection as being decoded, failing if it contains itself
+func (d *decoder) enter(ref uint64) error {
+	if d.visiting[ref] {
+		return fmt.Errorf("object %d contains itself", ref)
+	}
+	d.visiting[ref] = true
+	return nil
+}
+
+// count returns the length of a data, string, array, set or dictionary
+// object and the position of its contents. Lengths of 15 and more follow the
+// marker as an integer object.
+func (d *decoder) count(offset uint64, info byte) (uint64, uint64, error) {
+	if info != 0x0f {
+		return uint64(info), offset + 1, nil
+	}
+
+	b, err := d.bytes(offset+1, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 || b[0]&0x0f > 3 {
+		return 0, 0, fmt.Errorf("invalid length at %d", offset)
+	}
+	size := uint64(1) << (b[0] & 0x0f)
+	n, err := d.bytes(offset+2, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readUint(n), offset + 2 + size, nil
+}
+
+// refs reads the object references of an array, set (per = 1) or
+// dictionary (per = 2, keys followed by values)
+func (d *decoder) refs(offset uint64, info byte, per uint64) ([]uint64, error) {
+	count, start, err := d.count(offset, info)
+	if err != nil {
+		return nil, err
+	}
+	if count > d.numObjects*per {
+		return nil, fmt.Errorf("collection at %d is larger than the object table", offset)
+	}
+
+	b, err := d.bytes(start, count*per*uint64(d.refSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count*per)
+	for i := range refs {
+		refs[i] = readUint(b[i*d.refSize : (i+1)*d.refSize])
+	}
+	return refs, nil
+}
+
+// decodeInt decodes a big-endian integer; 8-byte integers are signed and
+// 16-byte integers hold unsigned 64-bit values in their low half
+func decodeInt(b []byte) (interface{}, error) {
+	switch len(b) {
+	case 1, 2, 4:
+		return int64(readUint(b)), nil
+	case 8:
+		return int64(binary.BigEndian.Uint64(b)), nil
+	case 16:
+		v := binary.BigEndian.Uint64(b[8:])
+		if binary.BigEndian.Uint64(b[:8]) == 0 && v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	}
+	return nil, fmt.Errorf("invalid integer of %d bytes", len(b))
+}
+
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// build assembles a binary property list from encoded objects, using
+// one-byte offsets and references; the first object is the top object
+func build(objects ...[]byte) []byte {
+	data := []byte(magic)
+	var offsets []byte
+	for _, object := range objects {
+		offsets = append(offsets, byte(len(data)))
+		data = append(data, object...)
+	}
+	tableOffset := len(data)
+	data = append(data, offsets...)
+
+	trailer := make([]byte, trailerSize)
+	trailer[6] = 1
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	return append(data, trailer...)
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want interface{}
+	}{
+		{"null", build([]byte{0x00}), nil},
+		{"true", build([]byte{0x09}), true},
+		{"int", build([]byte{0x11, 0x01, 0x00}), int64(256)},
+		{"negative int", build([]byte{0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}), int64(-2)},
+		{"ascii string", build([]byte{0x52, 'h', 'i'}), "hi"},
+		{"utf-16 string", build([]byte{0x61, 0x00, 0xe9}), "é"},
+		{"long string", build(append([]byte{0x5f, 0x10, 0x10}, strings.Repeat("x", 16)...)), strings.Repeat("x", 16)},
+		{"data", build([]byte{0x42, 0x01, 0x02}), []byte{0x01, 0x02}},
+		{"uid", build([]byte{0x80, 0x07}), UID(7)},
+		{"array", build([]byte{0xa2, 0x01, 0x02}, []byte{0x09}, []byte{0x51, 'a'}), []interface{}{true, "a"}},
+		{"dictionary", build([]byte{0xd1, 0x01, 0x02}, []byte{0x51, 'k'}, []byte{0x10, 0x05}), map[string]interface{}{"k": int64(5)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	// A UTF-16 string whose count of 2^63+1 wraps to 2 bytes when doubled,
+	// followed by those 2 bytes
+	hugeUTF16 := []byte{0x6f, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 0x01, 0x00, 0x41}
+	valid := build([]byte{0x52, 'h', 'i'})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not binary", []byte("<?xml version")},
+		{"truncated", []byte(magic + "short")},
+		{"overflowing utf-16 count", build(hugeUTF16)},
+		{"huge data count", build([]byte{0x4f, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})},
+		{"string past table", build([]byte{0x5e, 'a'})},
+		{"huge array count", build([]byte{0xaf, 0x13, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})},
+		{"reference out of range", build([]byte{0xa1, 0x05})},
+		{"cycle", build([]byte{0xa1, 0x00})},
+		{"bad offset size", func() []byte {
+			data := bytes.Clone(valid)
+			data[len(data)-trailerSize+6] = 9
+			return data
+		}()},
+		{"top object out of range", func() []byte {
+			data := bytes.Clone(valid)
+			data[len(data)-trailerSize+23] = 1
+			return data
+		}()},
+		{"table offset past end", func() []byte {
+			data := bytes.Clone(valid)
+			binary.BigEndian.PutUint64(data[len(data)-8:], 1<<62)
+			return data
+		}()},
+		{"unknown type", build([]byte{0x70})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decode(tt.data); err == nil {
+				t.Errorf("Decode = %#v, want an error", got)
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(build([]byte{0x52, 'h', 'i'}))
+	f.Add(build([]byte{0xd1, 0x01, 0x02}, []byte{0x51, 'k'}, []byte{0x10, 0x05}))
+	f.Add(build([]byte{0x6f, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 0x01, 0x00, 0x41}))
+	f.Add(build([]byte{0xa2, 0x01, 0x02}, []byte{0x61, 0x00, 0xe9}, []byte{0x33, 0, 0, 0, 0, 0, 0, 0, 0}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Decoding must fail cleanly rather than panic on any input
+		Decode(data)
+	})
+}
@@ -0,0 +1,217 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gkwa/sunlitsparrow/internal/table"
+)
+
+// Format selects how results are written
+type Format string
+
+const (
+	FormatTable  Format = "table"
+	FormatJSON   Format = "json"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// maxCellWidth is the widest a table column gets before values are cut,
+// measured in terminal cells
+const maxCellWidth = 50
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatTable, FormatJSON, FormatCSV, FormatNDJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected table, json, csv or ndjson)", name)
+}
+
+// Write writes the result in the given format
+func (r *Result) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		return r.writeJSON(w)
+	case FormatCSV:
+		return r.writeCSV(w)
+	case FormatNDJSON:
+		return r.writeNDJSON(w)
+	}
+	return r.writeTable(w)
+}
+
+func (r *Result) writeTable(w io.Writer) error {
+	columns := make([]table.Column, len(r.Columns))
+	for i, name := range r.Columns {
+		columns[i] = table.Column{Header: name, Max: maxCellWidth, Flex: true}
+		if r.numeric(i) {
+			columns[i].Align = table.AlignRight
+		}
+	}
+
+	t := table.New(columns...)
+	for _, row := range r.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = tableText(value)
+		}
+		t.Append(cells...)
+	}
+	if err := t.Render(w); err != nil {
+		return err
+	}
+
+	if len(r.Rows) == 1 {
+		_, err := io.WriteString(w, "(1 row)\n")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "(%d rows)\n", len(r.Rows))
+	return err
+}
+
+// numeric reports whether every non-NULL value of a column is a number
+func (r *Result) numeric(column int) bool {
+	found := false
+	for _, row := range r.Rows {
+		switch row[column].(type) {
+		case nil:
+		case int64, float64:
+			found = true
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+func (r *Result) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(r.Columns); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = plainText(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Result) writeJSON(w io.Writer) error {
+	objects := make([]json.RawMessage, len(r.Rows))
+	for i, row := range r.Rows {
+		object, err := r.object(row)
+		if err != nil {
+			return err
+		}
+		objects[i] = object
+	}
+
+	jsonData, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error formatting JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+func (r *Result) writeNDJSON(w io.Writer) error {
+	for _, row := range r.Rows {
+		object, err := r.object(row)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(object)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// object encodes a row as a JSON object with keys in column order; repeated
+// column names get a numeric suffix
+func (r *Result) object(row []interface{}) (json.RawMessage, error) {
+	var b bytes.Buffer
+	seen := map[string]int{}
+
+	b.WriteByte('{')
+	for i, column := range r.Columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		seen[column]++
+		if n := seen[column]; n > 1 {
+			column = fmt.Sprintf("%s_%d", column, n)
+		}
+
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(jsonValue(row[i]))
+		if err != nil {
+			return nil, fmt.Errorf("error formatting column %s: %w", column, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+// jsonValue converts text stored as BLOB to a string; other BLOBs are
+// encoded as base64 by encoding/json
+func jsonValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok && utf8.Valid(b) {
+		return string(b)
+	}
+	return value
+}
+
+// plainText formats a value for CSV: NULL is empty, binary data is base64
+func plainText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// tableText formats a value for a single table cell
+func tableText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if !utf8.Valid(v) {
+			return fmt.Sprintf("<%d bytes>", len(v))
+		}
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	}
+	return plainText(value)
+}
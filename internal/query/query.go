@@ -0,0 +1,183 @@
+// Package query runs ad-hoc SQL queries against the Maccy database and
+// formats their results.
+package query
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Options controls how query results are converted
+type Options struct {
+	// RawTimes disables converting Cocoa timestamps to times
+	RawTimes bool
+}
+
+// Result holds the columns and rows returned by a query
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+var cocoaEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// cocoaColumnName matches names of columns or expressions that hold Core
+// Data timestamps, such as ZLASTCOPIEDAT or MAX(lastCopiedAt) AS last_date
+var cocoaColumnName = regexp.MustCompile(`(?i)(copiedat|date|timestamp)$`)
+
+// Cocoa timestamps are only converted within 1970 to 2100
+const (
+	minCocoaSeconds = -978307200
+	maxCocoaSeconds = 3124137600
+)
+
+// Run executes a single SQL statement and collects its rows. Numeric values
+// of columns declared as timestamps or named like one are taken as seconds
+// since 2001-01-01 and converted to times unless opts.RawTimes is set.
+func Run(db *sql.DB, statement string, opts Options) (*Result, error) {
+	statement, err := SingleStatement(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(statement)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrAuth {
+		return nil, fmt.Errorf("only read-only queries are allowed: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	cocoa := make([]bool, len(columns))
+	for i, column := range columns {
+		declared := strings.ToUpper(types[i].DatabaseTypeName())
+		cocoa[i] = !opts.RawTimes && (strings.Contains(declared, "TIMESTAMP") || strings.Contains(declared, "DATE") ||
+			cocoaColumnName.MatchString(column))
+	}
+
+	result := &Result{Columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i := range values {
+			if cocoa[i] {
+				values[i] = cocoaTime(values[i])
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+
+	return result, rows.Err()
+}
+
+// cocoaTime converts a number of seconds since 2001 to a time, leaving other
+// values alone
+func cocoaTime(value interface{}) interface{} {
+	var seconds float64
+	switch v := value.(type) {
+	case float64:
+		seconds = v
+	case int64:
+		seconds = float64(v)
+	case time.Time:
+		// The driver reads integers of TIMESTAMP columns as Unix times,
+		// while Core Data writes them relative to 2001
+		if v.Nanosecond() != 0 {
+			return value
+		}
+		seconds = float64(v.Unix())
+	default:
+		return value
+	}
+
+	if math.IsNaN(seconds) || seconds < minCocoaSeconds || seconds > maxCocoaSeconds {
+		return value
+	}
+	whole, frac := math.Modf(seconds)
+	return cocoaEpoch.Add(time.Duration(whole)*time.Second + time.Duration(frac*float64(time.Second))).Local()
+}
+
+// SingleStatement returns statement without trailing semicolons, or an error
+// if it contains more than one statement
+func SingleStatement(statement string) (string, error) {
+	end := -1
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		if end >= 0 && !isSpace(c) && !strings.HasPrefix(statement[i:], "--") && !strings.HasPrefix(statement[i:], "/*") && c != ';' {
+			return "", errors.New("only a single SQL statement is allowed")
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			j := strings.IndexByte(statement[i+1:], c)
+			if j < 0 {
+				return "", errors.New("unterminated quote in SQL")
+			}
+			i += j + 1
+		case '[':
+			j := strings.IndexByte(statement[i+1:], ']')
+			if j < 0 {
+				return "", errors.New("unterminated identifier in SQL")
+			}
+			i += j + 1
+		case '-':
+			if strings.HasPrefix(statement[i:], "--") {
+				j := strings.IndexByte(statement[i:], '\n')
+				if j < 0 {
+					i = len(statement)
+				} else {
+					i += j
+				}
+			}
+		case '/':
+			if strings.HasPrefix(statement[i:], "/*") {
+				j := strings.Index(statement[i+2:], "*/")
+				if j < 0 {
+					i = len(statement)
+				} else {
+					i += j + 3
+				}
+			}
+		case ';':
+			if end < 0 {
+				end = i
+			}
+		}
+	}
+
+	if end >= 0 {
+		statement = statement[:end]
+	}
+	if strings.TrimSpace(statement) == "" {
+		return "", errors.New("empty SQL statement")
+	}
+	return statement, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
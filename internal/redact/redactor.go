@@ -0,0 +1,248 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/secrets"
+)
+
+// Kinds of data the redactor can mask
+const (
+	KindSecrets = "secrets"
+	KindEmails  = "emails"
+	KindPhones  = "phones"
+	KindCards   = "cards"
+	KindAll     = "all"
+)
+
+// Kinds lists the selectable redaction kinds
+var Kinds = []string{KindSecrets, KindEmails, KindPhones, KindCards}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)|\b\d{2,4})[\s.-]\d{3,4}[\s.-]\d{3,4}\b`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Options selects what the redactor masks
+type Options struct {
+	// Kinds holds entries of Kinds, or KindAll
+	Kinds []string
+	// Patterns are additional regular expressions to mask
+	Patterns []string
+	// Rules are the compiled secret detection rules used for KindSecrets
+	Rules []secrets.Rule
+}
+
+// Record lists what was masked in one history item
+type Record struct {
+	ItemID  int            `json:"itemId"`
+	Matches map[string]int `json:"matches"`
+}
+
+// Redactor masks sensitive data in history items
+type Redactor struct {
+	scanner   *secrets.Scanner
+	detectors []detector
+}
+
+// detector finds one kind of sensitive data
+type detector struct {
+	label   string
+	pattern *regexp.Regexp
+	// validate receives the whole text and the match bounds
+	validate func(text string, start, end int) bool
+}
+
+// span is a masked region of text
+type span struct {
+	start, end int
+	label      string
+}
+
+// NewRedactor creates a redactor from the given options
+func NewRedactor(opts Options) (*Redactor, error) {
+	r := &Redactor{}
+
+	for _, kind := range opts.Kinds {
+		switch strings.ToLower(kind) {
+		case KindAll:
+			r.scanner = secrets.NewScanner(opts.Rules)
+			r.detectors = append(r.detectors, emailDetector(), phoneDetector(), cardDetector())
+		case KindSecrets:
+			r.scanner = secrets.NewScanner(opts.Rules)
+		case KindEmails:
+			r.detectors = append(r.detectors, emailDetector())
+		case KindPhones:
+			r.detectors = append(r.detectors, phoneDetector())
+		case KindCards:
+			r.detectors = append(r.detectors, cardDetector())
+		default:
+			return nil, fmt.Errorf("unknown redaction kind %q (expected %s or %s)", kind, strings.Join(Kinds, ", "), KindAll)
+		}
+	}
+
+	for i, expr := range opts.Patterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+		}
+		r.detectors = append(r.detectors, detector{label: fmt.Sprintf("pattern-%d", i+1), pattern: pattern})
+	}
+
+	return r, nil
+}
+
+// RedactText returns text with sensitive data masked
+func (r *Redactor) RedactText(text string) string {
+	return r.redactText(text, map[string]int{})
+}
+
+// RedactItems returns copies of the items with sensitive data masked, along
+// with a record for every item that was changed
+func (r *Redactor) RedactItems(items []history.HistoryItem) ([]history.HistoryItem, []Record) {
+	redacted := make([]history.HistoryItem, len(items))
+	records := []Record{}
+
+	for i, item := range items {
+		var record *Record
+		redacted[i], record = r.RedactItem(item)
+		if record != nil {
+			records = append(records, *record)
+		}
+	}
+
+	return redacted, records
+}
+
+// RedactItem returns a copy of the item with sensitive data masked in its
+// title and textual contents. The record is nil when nothing was masked.
+func (r *Redactor) RedactItem(item history.HistoryItem) (history.HistoryItem, *Record) {
+	matches := map[string]int{}
+
+	item.Title = r.redactText(item.Title, matches)
+
+	contents := make([]history.Content, len(item.Contents))
+	for i, content := range item.Contents {
+		if content.IsText() {
+			content.Value = []byte(r.redactText(string(content.Value), matches))
+		}
+		contents[i] = content
+	}
+	item.Contents = contents
+
+	if len(matches) == 0 {
+		return item, nil
+	}
+	return item, &Record{ItemID: item.ID, Matches: matches}
+}
+
+// redactText masks all detected spans in text, counting them per label
+func (r *Redactor) redactText(text string, matches map[string]int) string {
+	var spans []span
+
+	if r.scanner != nil {
+		for _, m := range r.scanner.ScanText(text) {
+			spans = append(spans, span{m.Start, m.End, m.Rule.ID})
+		}
+	}
+	for _, d := range r.detectors {
+		for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+			if d.validate != nil && !d.validate(text, loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{loc[0], loc[1], d.label})
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	// Prefer the longest span among those starting at the same position
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString("[REDACTED:" + s.label + "]")
+		matches[s.label]++
+		last = s.end
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+func emailDetector() detector {
+	return detector{label: "email", pattern: emailPattern}
+}
+
+func phoneDetector() detector {
+	return detector{label: "phone", pattern: phonePattern, validate: func(text string, start, end int) bool {
+		// Reject groups that are only part of a longer number, such as a card
+		if partOfLongerNumber(text, start, end) {
+			return false
+		}
+		digits := digitsOf(text[start:end])
+		return len(digits) >= 9 && len(digits) <= 15
+	}}
+}
+
+func cardDetector() detector {
+	return detector{label: "credit-card", pattern: cardPattern, validate: func(text string, start, end int) bool {
+		return luhnValid(digitsOf(text[start:end]))
+	}}
+}
+
+// partOfLongerNumber reports whether the digits at text[start:end] continue
+// into adjacent digit groups separated by a single space or dash
+func partOfLongerNumber(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	isSep := func(i int) bool { return i >= 0 && i < len(text) && (text[i] == ' ' || text[i] == '-') }
+
+	return (isSep(end) && isDigit(end+1)) || (isSep(start-1) && isDigit(start-2))
+}
+
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// luhnValid reports whether a string of digits passes the Luhn checksum
+func luhnValid(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
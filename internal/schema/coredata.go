@@ -0,0 +1,223 @@
+package schema
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gkwa/sunlitsparrow/internal/plist"
+)
+
+// ErrNotCoreData is returned by CoreData for databases without Core Data
+// store metadata
+var ErrNotCoreData = errors.New("database is not a Core Data store (no Z_METADATA table)")
+
+// CoreDataMetadata is the store information Core Data and SwiftData keep in
+// Z_METADATA, Z_PRIMARYKEY and Z_MODELCACHE
+type CoreDataMetadata struct {
+	Version          int                    `json:"version,omitempty" yaml:"version,omitempty"`
+	UUID             string                 `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	StoreType        string                 `json:"storeType,omitempty" yaml:"storeType,omitempty"`
+	FrameworkVersion int64                  `json:"frameworkVersion,omitempty" yaml:"frameworkVersion,omitempty"`
+	ModelIdentifiers []string               `json:"modelIdentifiers,omitempty" yaml:"modelIdentifiers,omitempty"`
+	HashesVersion    int64                  `json:"hashesVersion,omitempty" yaml:"hashesVersion,omitempty"`
+	ModelChecksum    string                 `json:"modelChecksum,omitempty" yaml:"modelChecksum,omitempty"`
+	Entities         []Entity               `json:"entities,omitempty" yaml:"entities,omitempty"`
+	ModelCache       *ModelCache            `json:"modelCache,omitempty" yaml:"modelCache,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	PlistError       string                 `json:"plistError,omitempty" yaml:"plistError,omitempty"`
+}
+
+// Entity is a Core Data entity with its model version hash and the highest
+// primary key it has assigned
+type Entity struct {
+	ID          int    `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	Super       int    `json:"super,omitempty" yaml:"super,omitempty"`
+	Max         int    `json:"max" yaml:"max"`
+	VersionHash string `json:"versionHash,omitempty" yaml:"versionHash,omitempty"`
+}
+
+// ModelCache describes the compiled model Core Data caches in Z_MODELCACHE
+type ModelCache struct {
+	Size   int    `json:"size" yaml:"size"`
+	Format string `json:"format" yaml:"format"`
+}
+
+// Metadata keys of the Z_METADATA property list that are decoded into fields
+const (
+	keyStoreType        = "NSStoreType"
+	keyStoreUUID        = "NSStoreUUID"
+	keyFrameworkVersion = "NSPersistenceFrameworkVersion"
+	keyIdentifiers      = "NSStoreModelVersionIdentifiers"
+	keyHashes           = "NSStoreModelVersionHashes"
+	keyHashesVersion    = "NSStoreModelVersionHashesVersion"
+	keyChecksum         = "NSStoreModelVersionChecksumKey"
+)
+
+// CoreData reads the Core Data store metadata: the Z_METADATA row with its
+// binary property list, the entities and counters of Z_PRIMARYKEY and the
+// size of the cached model. A property list that cannot be decoded is
+// reported in PlistError rather than failing.
+func (e *Explorer) CoreData() (*CoreDataMetadata, error) {
+	names, err := e.tableNames()
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]bool{}
+	for _, name := range names {
+		tables[name] = true
+	}
+	if !tables["Z_METADATA"] {
+		return nil, ErrNotCoreData
+	}
+
+	meta := &CoreDataMetadata{}
+
+	var version sql.NullInt64
+	var uuid sql.NullString
+	var data []byte
+	err = e.db.QueryRow("SELECT Z_VERSION, Z_UUID, Z_PLIST FROM Z_METADATA LIMIT 1").Scan(&version, &uuid, &data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error reading Z_METADATA: %w", err)
+	}
+	meta.Version = int(version.Int64)
+	meta.UUID = uuid.String
+
+	hashes := map[string]string{}
+	if len(data) > 0 {
+		hashes = meta.decodePlist(data)
+	}
+
+	if tables["Z_PRIMARYKEY"] {
+		if meta.Entities, err = e.entities(hashes); err != nil {
+			return nil, err
+		}
+	}
+
+	// Entities known to the model but never assigned a primary key
+	var unassigned []string
+	for name := range hashes {
+		if !meta.hasEntity(name) {
+			unassigned = append(unassigned, name)
+		}
+	}
+	sort.Strings(unassigned)
+	for _, name := range unassigned {
+		meta.Entities = append(meta.Entities, Entity{Name: name, VersionHash: hashes[name]})
+	}
+
+	if tables["Z_MODELCACHE"] {
+		if meta.ModelCache, err = e.modelCache(); err != nil {
+			return nil, err
+		}
+	}
+
+	return meta, nil
+}
+
+// decodePlist fills the metadata from the Z_PLIST property list, keeping
+// unknown keys in Metadata, and returns the entity version hashes
+func (m *CoreDataMetadata) decodePlist(data []byte) map[string]string {
+	hashes := map[string]string{}
+
+	value, err := plist.Decode(data)
+	if err != nil {
+		m.PlistError = err.Error()
+		return hashes
+	}
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		m.PlistError = fmt.Sprintf("expected a dictionary, got %T", value)
+		return hashes
+	}
+
+	for key, value := range dict {
+		switch key {
+		case keyStoreType:
+			m.StoreType, _ = value.(string)
+		case keyStoreUUID:
+			if s, ok := value.(string); ok && m.UUID == "" {
+				m.UUID = s
+			}
+		case keyFrameworkVersion:
+			m.FrameworkVersion, _ = value.(int64)
+		case keyHashesVersion:
+			m.HashesVersion, _ = value.(int64)
+		case keyChecksum:
+			m.ModelChecksum, _ = value.(string)
+		case keyIdentifiers:
+			values, _ := value.([]interface{})
+			for _, v := range values {
+				if s, ok := v.(string); ok {
+					m.ModelIdentifiers = append(m.ModelIdentifiers, s)
+				}
+			}
+		case keyHashes:
+			entities, _ := value.(map[string]interface{})
+			for name, hash := range entities {
+				if b, ok := hash.([]byte); ok {
+					hashes[name] = base64.StdEncoding.EncodeToString(b)
+				}
+			}
+		default:
+			if m.Metadata == nil {
+				m.Metadata = map[string]interface{}{}
+			}
+			m.Metadata[key] = value
+		}
+	}
+
+	return hashes
+}
+
+func (m *CoreDataMetadata) hasEntity(name string) bool {
+	for _, entity := range m.Entities {
+		if entity.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// entities reads Z_PRIMARYKEY, attaching the version hash of each entity
+func (e *Explorer) entities(hashes map[string]string) ([]Entity, error) {
+	rows, err := e.db.Query("SELECT Z_ENT, Z_NAME, COALESCE(Z_SUPER, 0), COALESCE(Z_MAX, 0) FROM Z_PRIMARYKEY ORDER BY Z_ENT")
+	if err != nil {
+		return nil, fmt.Errorf("error reading Z_PRIMARYKEY: %w", err)
+	}
+	defer rows.Close()
+
+	var entities []Entity
+	for rows.Next() {
+		var entity Entity
+		if err := rows.Scan(&entity.ID, &entity.Name, &entity.Super, &entity.Max); err != nil {
+			return nil, fmt.Errorf("error scanning entity: %w", err)
+		}
+		entity.VersionHash = hashes[entity.Name]
+		entities = append(entities, entity)
+	}
+
+	return entities, rows.Err()
+}
+
+// modelCache describes the cached model without decoding it; Core Data
+// stores it compressed in current releases
+func (e *Explorer) modelCache() (*ModelCache, error) {
+	var data []byte
+	err := e.db.QueryRow("SELECT Z_CONTENT FROM Z_MODELCACHE LIMIT 1").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading Z_MODELCACHE: %w", err)
+	}
+
+	cache := &ModelCache{Size: len(data), Format: "compressed or unknown"}
+	if plist.IsBinary(data) {
+		cache.Format = "binary property list"
+	}
+	return cache, nil
+}
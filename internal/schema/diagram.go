@@ -0,0 +1,239 @@
+package schema
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DiagramOptions controls diagram generation
+type DiagramOptions struct {
+	// HideBookkeeping leaves out the Z_ENT and Z_OPT columns Core Data adds
+	// to every entity table and the tables used only for store metadata
+	HideBookkeeping bool
+}
+
+// bookkeepingColumns are maintained by Core Data rather than the app model
+var bookkeepingColumns = []string{"Z_ENT", "Z_OPT"}
+
+// bookkeepingTables hold store metadata and persistent history rather than
+// app data
+var bookkeepingTables = []string{"Z_METADATA", "Z_MODELCACHE", "Z_PRIMARYKEY", "ACHANGE", "ATRANSACTION", "ATRANSACTIONSTRING"}
+
+// relation is an edge of the diagram from a referencing column to the
+// referenced table
+type relation struct {
+	Table     string
+	Column    string
+	RefTable  string
+	RefColumn string
+	// Inferred is set for relations guessed from column names, as Core Data
+	// does not declare foreign keys
+	Inferred bool
+}
+
+// WriteDOT writes the schema as a Graphviz DOT graph with one record node
+// per table
+func WriteDOT(w io.Writer, s *Schema, opts DiagramOptions) error {
+	tables := diagramTables(s, opts)
+
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("  graph [rankdir=LR];\n")
+	b.WriteString("  node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [arrowhead=crow, arrowtail=tee, dir=both];\n\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(&b, "  %s [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">\n", dotID(table.Name))
+		fmt.Fprintf(&b, "    <TR><TD BGCOLOR=\"lightgrey\"><B>%s</B></TD></TR>\n", html.EscapeString(table.Name))
+		for _, column := range table.Columns {
+			label := column.Name + " " + column.Type
+			if column.PrimaryKey {
+				label += " PK"
+			}
+			fmt.Fprintf(&b, "    <TR><TD PORT=%q ALIGN=\"LEFT\">%s</TD></TR>\n", column.Name, html.EscapeString(strings.TrimSpace(label)))
+		}
+		b.WriteString("  </TABLE>>];\n")
+	}
+
+	edges := relations(tables)
+	if len(edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, r := range edges {
+		style := ""
+		if r.Inferred {
+			style = " [style=dashed]"
+		}
+		ref := dotID(r.RefTable)
+		if r.RefColumn != "" {
+			ref += ":" + dotID(r.RefColumn)
+		}
+		fmt.Fprintf(&b, "  %s -> %s:%s%s;\n", ref, dotID(r.Table), dotID(r.Column), style)
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the schema as a Mermaid entity-relationship diagram
+func WriteMermaid(w io.Writer, s *Schema, opts DiagramOptions) error {
+	tables := diagramTables(s, opts)
+	edges := relations(tables)
+
+	referencing := map[string]bool{}
+	for _, r := range edges {
+		referencing[r.Table+"."+r.Column] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(&b, "  %s {\n", mermaidID(table.Name))
+		for _, column := range table.Columns {
+			var keys []string
+			if column.PrimaryKey {
+				keys = append(keys, "PK")
+			}
+			if referencing[table.Name+"."+column.Name] {
+				keys = append(keys, "FK")
+			}
+			columnType := mermaidID(column.Type)
+			if column.Type == "" {
+				columnType = "ANY"
+			}
+			line := fmt.Sprintf("    %s %s %s", columnType, mermaidID(column.Name), strings.Join(keys, ", "))
+			b.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, r := range edges {
+		// Mermaid draws inferred relations as non-identifying (dotted)
+		line := "--"
+		if r.Inferred {
+			line = ".."
+		}
+		fmt.Fprintf(&b, "  %s ||%so{ %s : %q\n", mermaidID(r.RefTable), line, mermaidID(r.Table), r.Column)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// relations returns the declared foreign keys of the tables, plus relations
+// inferred from the names of undeclared integer columns: a column named Z<NAME>
+// (Core Data) or <name>_id refers to the single other table whose name ends
+// with NAME
+func relations(tables []Table) []relation {
+	var result []relation
+	for _, table := range tables {
+		declared := map[string]bool{}
+		for _, fk := range table.ForeignKeys {
+			// References to tables left out of the diagram are dropped
+			if findTable(tables, fk.Table) == nil {
+				continue
+			}
+			for i, column := range fk.Columns {
+				declared[column] = true
+				ref := ""
+				if i < len(fk.References) {
+					ref = fk.References[i]
+				} else if target := findTable(tables, fk.Table); len(target.PrimaryKey) > i {
+					ref = target.PrimaryKey[i]
+				}
+				result = append(result, relation{Table: table.Name, Column: column, RefTable: fk.Table, RefColumn: ref})
+			}
+		}
+
+		for _, column := range table.Columns {
+			if column.PrimaryKey || declared[column.Name] || !strings.Contains(strings.ToUpper(column.Type), "INT") {
+				continue
+			}
+			if target := inferTarget(tables, table.Name, column.Name); target != nil {
+				result = append(result, relation{
+					Table:     table.Name,
+					Column:    column.Name,
+					RefTable:  target.Name,
+					RefColumn: target.PrimaryKey[0],
+					Inferred:  true,
+				})
+			}
+		}
+	}
+	return result
+}
+
+var (
+	coreDataReference = regexp.MustCompile(`^Z([A-Z0-9]+)$`)
+	plainReference    = regexp.MustCompile(`(?i)^([a-z0-9]+)_id$`)
+)
+
+// inferTarget guesses the table a column refers to from its name
+func inferTarget(tables []Table, from, column string) *Table {
+	m := coreDataReference.FindStringSubmatch(column)
+	if m == nil {
+		m = plainReference.FindStringSubmatch(column)
+	}
+	if m == nil {
+		return nil
+	}
+
+	var target *Table
+	for i := range tables {
+		t := &tables[i]
+		if t.Name == from || len(t.PrimaryKey) != 1 || !strings.HasSuffix(strings.ToLower(t.Name), strings.ToLower(m[1])) {
+			continue
+		}
+		if target != nil {
+			return nil
+		}
+		target = t
+	}
+	return target
+}
+
+func findTable(tables []Table, name string) *Table {
+	for i := range tables {
+		if tables[i].Name == name {
+			return &tables[i]
+		}
+	}
+	return nil
+}
+
+// diagramTables returns the tables to draw, without bookkeeping if requested
+func diagramTables(s *Schema, opts DiagramOptions) []Table {
+	if !opts.HideBookkeeping {
+		return s.Tables
+	}
+
+	var tables []Table
+	for _, table := range s.Tables {
+		if slices.Contains(bookkeepingTables, table.Name) {
+			continue
+		}
+		table.Columns = slices.DeleteFunc(slices.Clone(table.Columns), func(c Column) bool {
+			return slices.Contains(bookkeepingColumns, c.Name)
+		})
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// dotID quotes a name for use as a DOT identifier
+func dotID(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+var mermaidInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// mermaidID replaces characters Mermaid does not accept in names and types
+func mermaidID(name string) string {
+	return strings.Trim(mermaidInvalid.ReplaceAllString(name, "_"), "_")
+}
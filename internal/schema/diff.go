@@ -0,0 +1,258 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Diff lists the differences between two schemas
+type Diff struct {
+	AddedTables     []string    `json:"addedTables,omitempty" yaml:"addedTables,omitempty"`
+	RemovedTables   []string    `json:"removedTables,omitempty" yaml:"removedTables,omitempty"`
+	ChangedTables   []TableDiff `json:"changedTables,omitempty" yaml:"changedTables,omitempty"`
+	AddedViews      []string    `json:"addedViews,omitempty" yaml:"addedViews,omitempty"`
+	RemovedViews    []string    `json:"removedViews,omitempty" yaml:"removedViews,omitempty"`
+	ChangedViews    []string    `json:"changedViews,omitempty" yaml:"changedViews,omitempty"`
+	AddedTriggers   []string    `json:"addedTriggers,omitempty" yaml:"addedTriggers,omitempty"`
+	RemovedTriggers []string    `json:"removedTriggers,omitempty" yaml:"removedTriggers,omitempty"`
+	ChangedTriggers []string    `json:"changedTriggers,omitempty" yaml:"changedTriggers,omitempty"`
+}
+
+// TableDiff lists the differences of a table present in both schemas
+type TableDiff struct {
+	Name               string         `json:"name" yaml:"name"`
+	AddedColumns       []Column       `json:"addedColumns,omitempty" yaml:"addedColumns,omitempty"`
+	RemovedColumns     []Column       `json:"removedColumns,omitempty" yaml:"removedColumns,omitempty"`
+	ChangedColumns     []ColumnChange `json:"changedColumns,omitempty" yaml:"changedColumns,omitempty"`
+	AddedIndexes       []Index        `json:"addedIndexes,omitempty" yaml:"addedIndexes,omitempty"`
+	RemovedIndexes     []Index        `json:"removedIndexes,omitempty" yaml:"removedIndexes,omitempty"`
+	ChangedIndexes     []IndexChange  `json:"changedIndexes,omitempty" yaml:"changedIndexes,omitempty"`
+	AddedForeignKeys   []ForeignKey   `json:"addedForeignKeys,omitempty" yaml:"addedForeignKeys,omitempty"`
+	RemovedForeignKeys []ForeignKey   `json:"removedForeignKeys,omitempty" yaml:"removedForeignKeys,omitempty"`
+}
+
+// ColumnChange is a column whose definition differs between the schemas
+type ColumnChange struct {
+	Name string `json:"name" yaml:"name"`
+	From Column `json:"from" yaml:"from"`
+	To   Column `json:"to" yaml:"to"`
+}
+
+// IndexChange is an index whose definition differs between the schemas
+type IndexChange struct {
+	Name string `json:"name" yaml:"name"`
+	From Index  `json:"from" yaml:"from"`
+	To   Index  `json:"to" yaml:"to"`
+}
+
+// Empty reports whether the schemas are identical
+func (d Diff) Empty() bool {
+	return reflect.DeepEqual(d, Diff{})
+}
+
+// Compare returns the changes needed to turn schema a into schema b
+func Compare(a, b *Schema) Diff {
+	var d Diff
+
+	oldTables := map[string]Table{}
+	for _, t := range a.Tables {
+		oldTables[t.Name] = t
+	}
+	newTables := map[string]bool{}
+	for _, t := range b.Tables {
+		newTables[t.Name] = true
+		old, ok := oldTables[t.Name]
+		if !ok {
+			d.AddedTables = append(d.AddedTables, t.Name)
+			continue
+		}
+		if td := compareTables(old, t); !reflect.DeepEqual(td, TableDiff{Name: t.Name}) {
+			d.ChangedTables = append(d.ChangedTables, td)
+		}
+	}
+	for _, t := range a.Tables {
+		if !newTables[t.Name] {
+			d.RemovedTables = append(d.RemovedTables, t.Name)
+		}
+	}
+
+	d.AddedViews, d.RemovedViews, d.ChangedViews = compareSQL(viewSQL(a.Views), viewSQL(b.Views))
+	d.AddedTriggers, d.RemovedTriggers, d.ChangedTriggers = compareSQL(triggerSQL(a.Triggers), triggerSQL(b.Triggers))
+	return d
+}
+
+func compareTables(a, b Table) TableDiff {
+	td := TableDiff{Name: b.Name}
+
+	oldColumns := map[string]Column{}
+	for _, c := range a.Columns {
+		oldColumns[c.Name] = c
+	}
+	newColumns := map[string]bool{}
+	for _, c := range b.Columns {
+		newColumns[c.Name] = true
+		old, ok := oldColumns[c.Name]
+		switch {
+		case !ok:
+			td.AddedColumns = append(td.AddedColumns, c)
+		case !reflect.DeepEqual(old, c):
+			td.ChangedColumns = append(td.ChangedColumns, ColumnChange{Name: c.Name, From: old, To: c})
+		}
+	}
+	for _, c := range a.Columns {
+		if !newColumns[c.Name] {
+			td.RemovedColumns = append(td.RemovedColumns, c)
+		}
+	}
+
+	oldIndexes := map[string]Index{}
+	for _, i := range a.Indexes {
+		oldIndexes[i.Name] = i
+	}
+	newIndexes := map[string]bool{}
+	for _, i := range b.Indexes {
+		newIndexes[i.Name] = true
+		old, ok := oldIndexes[i.Name]
+		switch {
+		case !ok:
+			td.AddedIndexes = append(td.AddedIndexes, i)
+		case !reflect.DeepEqual(old, i):
+			td.ChangedIndexes = append(td.ChangedIndexes, IndexChange{Name: i.Name, From: old, To: i})
+		}
+	}
+	for _, i := range a.Indexes {
+		if !newIndexes[i.Name] {
+			td.RemovedIndexes = append(td.RemovedIndexes, i)
+		}
+	}
+
+	// Foreign keys have no names, so they are matched by their definition
+	oldKeys := map[string]bool{}
+	for _, fk := range a.ForeignKeys {
+		oldKeys[describeForeignKey(fk)] = true
+	}
+	newKeys := map[string]bool{}
+	for _, fk := range b.ForeignKeys {
+		newKeys[describeForeignKey(fk)] = true
+		if !oldKeys[describeForeignKey(fk)] {
+			td.AddedForeignKeys = append(td.AddedForeignKeys, fk)
+		}
+	}
+	for _, fk := range a.ForeignKeys {
+		if !newKeys[describeForeignKey(fk)] {
+			td.RemovedForeignKeys = append(td.RemovedForeignKeys, fk)
+		}
+	}
+
+	return td
+}
+
+// compareSQL compares named SQL definitions, keeping the order of names in b
+// followed by a
+func compareSQL(a, b []namedSQL) (added, removed, changed []string) {
+	old := map[string]string{}
+	for _, o := range a {
+		old[o.name] = o.sql
+	}
+	seen := map[string]bool{}
+	for _, o := range b {
+		seen[o.name] = true
+		sql, ok := old[o.name]
+		switch {
+		case !ok:
+			added = append(added, o.name)
+		case sql != o.sql:
+			changed = append(changed, o.name)
+		}
+	}
+	for _, o := range a {
+		if !seen[o.name] {
+			removed = append(removed, o.name)
+		}
+	}
+	return added, removed, changed
+}
+
+type namedSQL struct {
+	name, sql string
+}
+
+func viewSQL(views []View) []namedSQL {
+	result := make([]namedSQL, len(views))
+	for i, v := range views {
+		result[i] = namedSQL{v.Name, v.SQL}
+	}
+	return result
+}
+
+func triggerSQL(triggers []Trigger) []namedSQL {
+	result := make([]namedSQL, len(triggers))
+	for i, t := range triggers {
+		result[i] = namedSQL{t.Name, t.SQL}
+	}
+	return result
+}
+
+// WriteDiffText writes a diff with one line per change, prefixed with "+"
+// for additions, "-" for removals and "~" for changes
+func WriteDiffText(w io.Writer, d Diff) error {
+	var b strings.Builder
+
+	for _, name := range d.AddedTables {
+		fmt.Fprintf(&b, "+ table %s\n", name)
+	}
+	for _, name := range d.RemovedTables {
+		fmt.Fprintf(&b, "- table %s\n", name)
+	}
+	for _, td := range d.ChangedTables {
+		fmt.Fprintf(&b, "~ table %s\n", td.Name)
+		for _, c := range td.AddedColumns {
+			fmt.Fprintf(&b, "    + column %s\n", describeColumn(c))
+		}
+		for _, c := range td.RemovedColumns {
+			fmt.Fprintf(&b, "    - column %s\n", describeColumn(c))
+		}
+		for _, c := range td.ChangedColumns {
+			fmt.Fprintf(&b, "    ~ column %s -> %s\n", describeColumn(c.From), describeColumn(c.To))
+		}
+		for _, i := range td.AddedIndexes {
+			fmt.Fprintf(&b, "    + index %s\n", describeIndex(i))
+		}
+		for _, i := range td.RemovedIndexes {
+			fmt.Fprintf(&b, "    - index %s\n", describeIndex(i))
+		}
+		for _, i := range td.ChangedIndexes {
+			fmt.Fprintf(&b, "    ~ index %s -> %s\n", describeIndex(i.From), describeIndex(i.To))
+		}
+		for _, fk := range td.AddedForeignKeys {
+			fmt.Fprintf(&b, "    + foreign key %s\n", describeForeignKey(fk))
+		}
+		for _, fk := range td.RemovedForeignKeys {
+			fmt.Fprintf(&b, "    - foreign key %s\n", describeForeignKey(fk))
+		}
+	}
+
+	for _, name := range d.AddedViews {
+		fmt.Fprintf(&b, "+ view %s\n", name)
+	}
+	for _, name := range d.RemovedViews {
+		fmt.Fprintf(&b, "- view %s\n", name)
+	}
+	for _, name := range d.ChangedViews {
+		fmt.Fprintf(&b, "~ view %s\n", name)
+	}
+	for _, name := range d.AddedTriggers {
+		fmt.Fprintf(&b, "+ trigger %s\n", name)
+	}
+	for _, name := range d.RemovedTriggers {
+		fmt.Fprintf(&b, "- trigger %s\n", name)
+	}
+	for _, name := range d.ChangedTriggers {
+		fmt.Fprintf(&b, "~ trigger %s\n", name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
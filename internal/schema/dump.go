@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DumpOptions selects what Dump writes
+type DumpOptions struct {
+	// Tables limits the dump to these tables; empty means all tables, views
+	// and triggers
+	Tables []string
+	// ItemIDs, when not nil, limits the rows of the tables in ItemColumns to
+	// those referencing one of these item IDs
+	ItemIDs map[int]bool
+	// ItemColumns maps tables holding item data to their item ID column
+	ItemColumns map[string]string
+	// Redact, when set, masks sensitive data in the text values and UTF-8
+	// BLOBs of the tables in ItemColumns
+	Redact func(string) string
+}
+
+// DumpStats counts what Dump wrote
+type DumpStats struct {
+	Tables int
+	Rows   int
+}
+
+// Dump writes a SQL script that recreates the database: tables with their
+// rows, then indexes, triggers and views. BLOBs are written as X'..'
+// literals using SQLite's quote() so values round-trip exactly.
+func (e *Explorer) Dump(w io.Writer, opts DumpOptions) (DumpStats, error) {
+	var stats DumpStats
+
+	tables, err := e.objects("table")
+	if err != nil {
+		return stats, err
+	}
+
+	selected := map[string]bool{}
+	for _, name := range opts.Tables {
+		if !slices.ContainsFunc(tables, func(o object) bool { return o.name == name }) {
+			return stats, fmt.Errorf("no such table: %s", name)
+		}
+		selected[name] = true
+	}
+	included := func(table string) bool {
+		return len(selected) == 0 || selected[table]
+	}
+
+	if _, err := io.WriteString(w, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n"); err != nil {
+		return stats, err
+	}
+
+	for _, table := range tables {
+		if !included(table.name) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n", table.sql); err != nil {
+			return stats, err
+		}
+
+		idColumn := ""
+		if opts.ItemIDs != nil {
+			idColumn = opts.ItemColumns[table.name]
+		}
+		var redact func(string) string
+		if _, ok := opts.ItemColumns[table.name]; ok {
+			redact = opts.Redact
+		}
+		rows, err := e.dumpRows(w, table.name, idColumn, opts.ItemIDs, redact)
+		if err != nil {
+			return stats, err
+		}
+		stats.Tables++
+		stats.Rows += rows
+	}
+
+	// Triggers come after the data so loading the dump does not fire them
+	for _, kind := range []string{"index", "trigger", "view"} {
+		objects, err := e.objects(kind)
+		if err != nil {
+			return stats, err
+		}
+		for _, o := range objects {
+			// Indexes created by constraints have no SQL of their own
+			if o.sql == "" || !included(o.table) || (kind == "view" && len(selected) > 0) {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s;\n", o.sql); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, "COMMIT;\n")
+	return stats, err
+}
+
+// dumpRows writes an INSERT statement per row of a table. When idColumn is
+// set, only rows whose value in that column is in ids are written. redact,
+// if not nil, is applied to every text value.
+func (e *Explorer) dumpRows(w io.Writer, table, idColumn string, ids map[int]bool, redact func(string) string) (int, error) {
+	columns, _, err := e.columns(table)
+	if err != nil {
+		return 0, err
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = fmt.Sprintf("quote(%s)", quoteIdentifier(column.Name))
+	}
+	// The item ID is selected first, or a constant when rows are not filtered
+	key := "0"
+	if idColumn != "" {
+		key = quoteIdentifier(idColumn)
+	}
+
+	rows, err := e.db.Query(fmt.Sprintf("SELECT %s, %s FROM %s", key, strings.Join(quoted, ", "), quoteIdentifier(table)))
+	if err != nil {
+		return 0, fmt.Errorf("error reading table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	values := make([]string, len(columns))
+	dest := make([]interface{}, len(columns)+1)
+	var id *int
+	dest[0] = &id
+	for i := range values {
+		dest[i+1] = &values[i]
+	}
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return count, fmt.Errorf("error scanning row of %s: %w", table, err)
+		}
+		if idColumn != "" && (id == nil || !ids[*id]) {
+			continue
+		}
+		if redact != nil {
+			for i, value := range values {
+				values[i] = redactLiteral(value, redact)
+			}
+		}
+		if _, err := fmt.Fprintf(w, "INSERT INTO %s VALUES(%s);\n", quoteIdentifier(table), strings.Join(values, ",")); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+// redactLiteral applies redact to a value quoted by SQLite's quote(): a
+// string literal, or a BLOB literal holding UTF-8 text without NUL bytes.
+// Numbers, NULL and binary BLOBs are returned unchanged.
+func redactLiteral(literal string, redact func(string) string) string {
+	switch {
+	case strings.HasPrefix(literal, "'"):
+		text := strings.ReplaceAll(literal[1:len(literal)-1], "''", "'")
+		return "'" + strings.ReplaceAll(redact(text), "'", "''") + "'"
+	case strings.HasPrefix(literal, "X'"):
+		data, err := hex.DecodeString(literal[2 : len(literal)-1])
+		if err != nil || !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return literal
+		}
+		return "X'" + strings.ToUpper(hex.EncodeToString([]byte(redact(string(data))))) + "'"
+	}
+	return literal
+}
+
+// quoteIdentifier quotes a table or column name for use in SQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
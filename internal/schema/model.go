@@ -0,0 +1,315 @@
+package schema
+
+import (
+	"fmt"
+)
+
+// Schema describes the structure of a database
+type Schema struct {
+	Tables   []Table   `json:"tables" yaml:"tables"`
+	Views    []View    `json:"views,omitempty" yaml:"views,omitempty"`
+	Triggers []Trigger `json:"triggers,omitempty" yaml:"triggers,omitempty"`
+}
+
+// Table describes a database table
+type Table struct {
+	Name        string       `json:"name" yaml:"name"`
+	Columns     []Column     `json:"columns" yaml:"columns"`
+	PrimaryKey  []string     `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	ForeignKeys []ForeignKey `json:"foreignKeys,omitempty" yaml:"foreignKeys,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+// Column describes a single table column
+type Column struct {
+	Name       string  `json:"name" yaml:"name"`
+	Type       string  `json:"type" yaml:"type"`
+	NotNull    bool    `json:"notNull,omitempty" yaml:"notNull,omitempty"`
+	PrimaryKey bool    `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	Default    *string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// ForeignKey describes a reference from columns of a table to another table
+type ForeignKey struct {
+	Columns    []string `json:"columns" yaml:"columns"`
+	Table      string   `json:"table" yaml:"table"`
+	References []string `json:"references" yaml:"references"`
+	OnUpdate   string   `json:"onUpdate,omitempty" yaml:"onUpdate,omitempty"`
+	OnDelete   string   `json:"onDelete,omitempty" yaml:"onDelete,omitempty"`
+}
+
+// Index describes an index on a table. Origin is "c" for CREATE INDEX, "u"
+// for a UNIQUE constraint and "pk" for a PRIMARY KEY constraint.
+type Index struct {
+	Name    string   `json:"name" yaml:"name"`
+	Columns []string `json:"columns" yaml:"columns"`
+	Unique  bool     `json:"unique,omitempty" yaml:"unique,omitempty"`
+	Origin  string   `json:"origin" yaml:"origin"`
+	Partial bool     `json:"partial,omitempty" yaml:"partial,omitempty"`
+}
+
+// View describes a view
+type View struct {
+	Name string `json:"name" yaml:"name"`
+	SQL  string `json:"sql" yaml:"sql"`
+}
+
+// Trigger describes a trigger on a table
+type Trigger struct {
+	Name  string `json:"name" yaml:"name"`
+	Table string `json:"table" yaml:"table"`
+	SQL   string `json:"sql" yaml:"sql"`
+}
+
+// Schema reads the complete schema of the database
+func (e *Explorer) Schema() (*Schema, error) {
+	tables, err := e.Tables()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{Tables: tables}
+
+	objects, err := e.objects("view")
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		s.Views = append(s.Views, View{Name: o.name, SQL: o.sql})
+	}
+
+	objects, err = e.objects("trigger")
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		s.Triggers = append(s.Triggers, Trigger{Name: o.name, Table: o.table, SQL: o.sql})
+	}
+
+	return s, nil
+}
+
+// Tables returns the user tables of the database together with their
+// columns, keys and indexes
+func (e *Explorer) Tables() ([]Table, error) {
+	names, err := e.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		table, err := e.table(name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// table reads the definition of one table
+func (e *Explorer) table(name string) (Table, error) {
+	table := Table{Name: name}
+
+	columns, pk, err := e.columns(name)
+	if err != nil {
+		return table, err
+	}
+	table.Columns = columns
+	table.PrimaryKey = pk
+
+	if table.ForeignKeys, err = e.foreignKeys(name); err != nil {
+		return table, err
+	}
+	if table.Indexes, err = e.indexes(name); err != nil {
+		return table, err
+	}
+
+	return table, nil
+}
+
+// tableNames lists the user tables of the database
+func (e *Explorer) tableNames() ([]string, error) {
+	objects, err := e.objects("table")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(objects))
+	for i, o := range objects {
+		names[i] = o.name
+	}
+	return names, nil
+}
+
+// object is an entry of sqlite_master
+type object struct {
+	name, table, sql string
+}
+
+// objects lists the user-defined entries of sqlite_master of one type
+func (e *Explorer) objects(kind string) ([]object, error) {
+	rows, err := e.db.Query(`
+		SELECT name, tbl_name, COALESCE(sql, '') FROM sqlite_master
+		WHERE type = ? AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("error querying %ss: %w", kind, err)
+	}
+	defer rows.Close()
+
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.name, &o.table, &o.sql); err != nil {
+			return nil, fmt.Errorf("error scanning %s: %w", kind, err)
+		}
+		objects = append(objects, o)
+	}
+
+	return objects, rows.Err()
+}
+
+// columns reads the column definitions of a table and the columns of its
+// primary key in key order
+func (e *Explorer) columns(table string) ([]Column, []string, error) {
+	rows, err := e.db.Query(fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting schema for table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	pkColumns := map[int]string{}
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, columnType string
+		var dfltValue *string
+
+		if err := rows.Scan(&cid, &name, &columnType, &notnull, &dfltValue, &pk); err != nil {
+			return nil, nil, fmt.Errorf("error scanning column data: %w", err)
+		}
+
+		columns = append(columns, Column{
+			Name:       name,
+			Type:       columnType,
+			NotNull:    notnull == 1,
+			PrimaryKey: pk > 0,
+			Default:    dfltValue,
+		})
+		if pk > 0 {
+			pkColumns[pk] = name
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var pk []string
+	for i := 1; i <= len(pkColumns); i++ {
+		pk = append(pk, pkColumns[i])
+	}
+	return columns, pk, nil
+}
+
+// foreignKeys reads the foreign keys of a table, combining the columns of
+// composite keys
+func (e *Explorer) foreignKeys(table string) ([]ForeignKey, error) {
+	rows, err := e.db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%q)", table))
+	if err != nil {
+		return nil, fmt.Errorf("error getting foreign keys for table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	byID := map[int]int{}
+	for rows.Next() {
+		var id, seq int
+		var toTable, from, onUpdate, onDelete, match string
+		var to *string
+
+		if err := rows.Scan(&id, &seq, &toTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, fmt.Errorf("error scanning foreign key data: %w", err)
+		}
+
+		i, ok := byID[id]
+		if !ok {
+			i = len(keys)
+			byID[id] = i
+			keys = append(keys, ForeignKey{Table: toTable, OnUpdate: onUpdate, OnDelete: onDelete})
+		}
+		keys[i].Columns = append(keys[i].Columns, from)
+		// A missing target column refers to the primary key of the other table
+		if to != nil {
+			keys[i].References = append(keys[i].References, *to)
+		}
+	}
+
+	return keys, rows.Err()
+}
+
+// indexes reads the indexes of a table with their columns
+func (e *Explorer) indexes(table string) ([]Index, error) {
+	rows, err := e.db.Query(fmt.Sprintf("PRAGMA index_list(%q)", table))
+	if err != nil {
+		return nil, fmt.Errorf("error getting indexes for table %s: %w", table, err)
+	}
+
+	var indexes []Index
+	for rows.Next() {
+		var seq, unique, partial int
+		var index Index
+
+		if err := rows.Scan(&seq, &index.Name, &unique, &index.Origin, &partial); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning index data: %w", err)
+		}
+		index.Unique = unique == 1
+		index.Partial = partial == 1
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		columns, err := e.indexColumns(indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Columns = columns
+	}
+
+	return indexes, nil
+}
+
+// indexColumns reads the columns of an index in index order; expression
+// columns are reported as "<expr>"
+func (e *Explorer) indexColumns(index string) ([]string, error) {
+	rows, err := e.db.Query(fmt.Sprintf("PRAGMA index_info(%q)", index))
+	if err != nil {
+		return nil, fmt.Errorf("error getting columns of index %s: %w", index, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var seqno, cid int
+		var name *string
+
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, fmt.Errorf("error scanning index column: %w", err)
+		}
+		if name == nil {
+			columns = append(columns, "<expr>")
+			continue
+		}
+		columns = append(columns, *name)
+	}
+
+	return columns, rows.Err()
+}
@@ -0,0 +1,191 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/table"
+)
+
+// WriteText writes a schema in a human-readable form
+func WriteText(w io.Writer, s *Schema) error {
+	var b strings.Builder
+
+	for _, table := range s.Tables {
+		fmt.Fprintf(&b, "Table: %s\n", table.Name)
+		for _, column := range table.Columns {
+			fmt.Fprintf(&b, "  - %s\n", describeColumn(column))
+		}
+		for _, fk := range table.ForeignKeys {
+			fmt.Fprintf(&b, "  - Foreign Key: %s\n", describeForeignKey(fk))
+		}
+		for _, index := range table.Indexes {
+			fmt.Fprintf(&b, "  - Index: %s\n", describeIndex(index))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, view := range s.Views {
+		fmt.Fprintf(&b, "View: %s\n  %s\n\n", view.Name, view.SQL)
+	}
+
+	for _, trigger := range s.Triggers {
+		fmt.Fprintf(&b, "Trigger: %s on %s\n  %s\n\n", trigger.Name, trigger.Table, trigger.SQL)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// describeColumn formats a column like "name (TYPE) NOT NULL PRIMARY KEY"
+func describeColumn(column Column) string {
+	desc := fmt.Sprintf("%s (%s)", column.Name, column.Type)
+	if column.NotNull {
+		desc += " NOT NULL"
+	}
+	if column.PrimaryKey {
+		desc += " PRIMARY KEY"
+	}
+	if column.Default != nil {
+		desc += " DEFAULT " + *column.Default
+	}
+	return desc
+}
+
+// describeIndex formats an index like "name (col1, col2) UNIQUE"
+func describeIndex(index Index) string {
+	desc := fmt.Sprintf("%s (%s)", index.Name, strings.Join(index.Columns, ", "))
+	if index.Unique {
+		desc += " UNIQUE"
+	}
+	if index.Partial {
+		desc += " PARTIAL"
+	}
+	return desc
+}
+
+// describeForeignKey formats a foreign key like "a, b -> table(x, y)"
+func describeForeignKey(fk ForeignKey) string {
+	desc := fmt.Sprintf("%s -> %s(%s)", strings.Join(fk.Columns, ", "), fk.Table, strings.Join(fk.References, ", "))
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		desc += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		desc += " ON DELETE " + fk.OnDelete
+	}
+	return desc
+}
+
+// WriteFingerprintText writes a layout fingerprint in a human-readable form
+func WriteFingerprintText(w io.Writer, fp *Fingerprint) error {
+	var b strings.Builder
+
+	status := "recognized"
+	if !fp.Recognized {
+		status = "not recognized"
+	}
+	fmt.Fprintf(&b, "Layout:      %s (%s)\n", fp.Layout, status)
+	if fp.Releases != "" {
+		fmt.Fprintf(&b, "Releases:    %s\n", fp.Releases)
+	}
+	fmt.Fprintf(&b, "Fingerprint: %s\n", fp.Hash)
+	if len(fp.Markers) > 0 {
+		fmt.Fprintf(&b, "Markers:     %s\n", strings.Join(fp.Markers, ", "))
+	}
+
+	if fp.CoreData != nil {
+		if fp.CoreData.Version != 0 || fp.CoreData.UUID != "" {
+			fmt.Fprintf(&b, "Store:       version %d, UUID %s\n", fp.CoreData.Version, fp.CoreData.UUID)
+		}
+		if len(fp.CoreData.ModelIdentifiers) > 0 {
+			fmt.Fprintf(&b, "Model:       %s\n", quoteAll(fp.CoreData.ModelIdentifiers))
+		}
+		for _, entity := range fp.CoreData.Entities {
+			fmt.Fprintf(&b, "Entity:      %s (%d, max primary key %d)\n", entity.Name, entity.ID, entity.Max)
+		}
+	}
+
+	if len(fp.Problems) > 0 {
+		b.WriteString("Problems:\n")
+		for _, problem := range fp.Problems {
+			fmt.Fprintf(&b, "  - %s\n", problem)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCoreDataText writes Core Data store metadata in a human-readable form
+func WriteCoreDataText(w io.Writer, m *CoreDataMetadata) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Store version:     %d\n", m.Version)
+	if m.UUID != "" {
+		fmt.Fprintf(&b, "Store UUID:        %s\n", m.UUID)
+	}
+	if m.StoreType != "" {
+		fmt.Fprintf(&b, "Store type:        %s\n", m.StoreType)
+	}
+	if m.FrameworkVersion != 0 {
+		fmt.Fprintf(&b, "Framework version: %d\n", m.FrameworkVersion)
+	}
+	if len(m.ModelIdentifiers) > 0 {
+		fmt.Fprintf(&b, "Model identifiers: %s\n", quoteAll(m.ModelIdentifiers))
+	}
+	if m.HashesVersion != 0 {
+		fmt.Fprintf(&b, "Hashes version:    %d\n", m.HashesVersion)
+	}
+	if m.ModelChecksum != "" {
+		fmt.Fprintf(&b, "Model checksum:    %s\n", m.ModelChecksum)
+	}
+	if m.ModelCache != nil {
+		fmt.Fprintf(&b, "Model cache:       %d bytes (%s)\n", m.ModelCache.Size, m.ModelCache.Format)
+	}
+	if m.PlistError != "" {
+		fmt.Fprintf(&b, "Metadata error:    %s\n", m.PlistError)
+	}
+
+	if len(m.Entities) > 0 {
+		t := table.New(
+			table.Column{Header: "ID", Align: table.AlignRight},
+			table.Column{Header: "Entity"},
+			table.Column{Header: "Super", Align: table.AlignRight},
+			table.Column{Header: "Max PK", Align: table.AlignRight},
+			table.Column{Header: "Version Hash"},
+		)
+		for _, entity := range m.Entities {
+			t.Append(strconv.Itoa(entity.ID), entity.Name, strconv.Itoa(entity.Super), strconv.Itoa(entity.Max), entity.VersionHash)
+		}
+		b.WriteString("\n")
+		t.Render(&b)
+	}
+
+	if len(m.Metadata) > 0 {
+		keys := make([]string, 0, len(m.Metadata))
+		for key := range m.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("\nOther metadata:\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  %s: %v\n", key, m.Metadata[key])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteAll quotes and joins strings so empty ones remain visible
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,253 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Layout names a known storage layout of the Maccy database
+type Layout string
+
+const (
+	// LayoutCoreData is the Core Data store written by Maccy before 2.0
+	LayoutCoreData Layout = "coredata"
+	// LayoutSwiftData is the SwiftData store written by Maccy 2.0 and later,
+	// which shares the Core Data tables and adds persistent history tracking
+	LayoutSwiftData Layout = "swiftdata"
+	// LayoutStandard is the plain HistoryItem/HistoryItemContent layout that
+	// sunlitsparrow also reads
+	LayoutStandard Layout = "standard"
+	// LayoutUnknown is any other layout
+	LayoutUnknown Layout = "unknown"
+)
+
+// knownLayout describes the tables and columns of a recognized layout
+type knownLayout struct {
+	layout Layout
+	// releases is the range of Maccy releases writing this layout, as far
+	// as the layout itself tells them apart
+	releases string
+	tables   map[string][]string
+	// markers are tables whose presence distinguishes the layout from
+	// others with the same history tables
+	markers []string
+}
+
+var coreDataTables = map[string][]string{
+	"ZHISTORYITEM":        {"Z_PK", "Z_ENT", "Z_OPT", "ZNUMBEROFCOPIES", "ZFIRSTCOPIEDAT", "ZLASTCOPIEDAT", "ZAPPLICATION", "ZPIN", "ZTITLE"},
+	"ZHISTORYITEMCONTENT": {"Z_PK", "Z_ENT", "Z_OPT", "ZITEM", "ZTYPE", "ZVALUE"},
+}
+
+// knownLayouts lists the layouts in the order they are checked
+var knownLayouts = []knownLayout{
+	{
+		layout:   LayoutSwiftData,
+		releases: "Maccy 2.0 and later",
+		tables:   coreDataTables,
+		markers:  []string{"ACHANGE", "ATRANSACTION"},
+	},
+	{
+		layout:   LayoutCoreData,
+		releases: "Maccy 0.x to 1.x",
+		tables:   coreDataTables,
+	},
+	{
+		layout:   LayoutStandard,
+		releases: "none (plain layout, not written by Maccy itself)",
+		tables: map[string][]string{
+			"HistoryItem":        {"id", "title", "pin", "firstCopiedAt", "lastCopiedAt", "numberOfCopies", "application"},
+			"HistoryItemContent": {"item_id", "type", "value"},
+		},
+	},
+}
+
+// swiftDataMarkers are tables created by the persistent history tracking
+// SwiftData enables by default
+var swiftDataMarkers = []string{"ACHANGE", "ATRANSACTION", "ATRANSACTIONSTRING"}
+
+// Fingerprint identifies the layout of a database
+type Fingerprint struct {
+	Hash       string            `json:"hash" yaml:"hash"`
+	Layout     Layout            `json:"layout" yaml:"layout"`
+	Releases   string            `json:"releases,omitempty" yaml:"releases,omitempty"`
+	Recognized bool              `json:"recognized" yaml:"recognized"`
+	Markers    []string          `json:"markers,omitempty" yaml:"markers,omitempty"`
+	CoreData   *CoreDataMetadata `json:"coreData,omitempty" yaml:"coreData,omitempty"`
+	Problems   []string          `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+// Detect fingerprints the database layout and classifies it by its history
+// tables, their columns and the tables marking SwiftData stores. The hash
+// identifies the exact layout in reports; it is not mapped to releases.
+func (e *Explorer) Detect() (*Fingerprint, error) {
+	tables, err := e.Tables()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]map[string]bool{}
+	for _, table := range tables {
+		columns[table.Name] = map[string]bool{}
+		for _, column := range table.Columns {
+			columns[table.Name][column.Name] = true
+		}
+	}
+
+	fp := &Fingerprint{Hash: layoutHash(tables)}
+	if columns["Z_METADATA"] != nil {
+		if fp.CoreData, err = e.CoreData(); err != nil {
+			return nil, err
+		}
+	}
+	fp.classify(columns)
+	return fp, nil
+}
+
+// CheckLayout classifies the layout like Detect, reading only the table
+// names and the columns of the history tables. It is cheap enough to run
+// whenever the database is opened; the result has no hash or Core Data
+// metadata.
+func (e *Explorer) CheckLayout() (*Fingerprint, error) {
+	names, err := e.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]map[string]bool{}
+	for _, name := range names {
+		columns[name] = map[string]bool{}
+		if !isHistoryTable(name) {
+			continue
+		}
+		tableColumns, _, err := e.columns(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range tableColumns {
+			columns[name][column.Name] = true
+		}
+	}
+
+	fp := &Fingerprint{}
+	fp.classify(columns)
+	return fp, nil
+}
+
+// classify sets the layout, markers and problems from the columns of each
+// table. The closest layout is the first one whose history tables all
+// exist; its problems explain why it is not recognized exactly.
+func (fp *Fingerprint) classify(columns map[string]map[string]bool) {
+	fp.Layout = LayoutUnknown
+	for _, marker := range swiftDataMarkers {
+		if columns[marker] != nil {
+			fp.Markers = append(fp.Markers, marker)
+		}
+	}
+
+	for _, known := range knownLayouts {
+		if !hasTables(columns, known.markers) || !hasTables(columns, tableNamesOf(known.tables)) {
+			continue
+		}
+		fp.Layout = known.layout
+		fp.Releases = known.releases
+		fp.Problems = columnProblems(columns, known.tables)
+		fp.Recognized = len(fp.Problems) == 0
+		return
+	}
+
+	fp.Problems = []string{"no known Maccy history tables found"}
+}
+
+// isHistoryTable reports whether a known layout expects columns of the table
+func isHistoryTable(name string) bool {
+	for _, known := range knownLayouts {
+		if _, ok := known.tables[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Warning returns a one-line warning for an unrecognized layout, or an empty
+// string when the layout is recognized
+func (fp *Fingerprint) Warning() string {
+	if fp.Recognized {
+		return ""
+	}
+	var layout []string
+	if fp.Hash != "" {
+		layout = append(layout, fp.Hash)
+	}
+	if fp.Layout != LayoutUnknown {
+		layout = append(layout, "closest to "+string(fp.Layout))
+	}
+	described := ""
+	if len(layout) > 0 {
+		described = " (" + strings.Join(layout, ", ") + ")"
+	}
+	return fmt.Sprintf("unrecognized Maccy database layout%s: %s; results may be incomplete",
+		described, strings.Join(fp.Problems, "; "))
+}
+
+// layoutHash hashes the names and types of all tables and columns, ignoring
+// their order
+func layoutHash(tables []Table) string {
+	var lines []string
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			lines = append(lines, fmt.Sprintf("%s.%s %s", table.Name, column.Name, strings.ToUpper(column.Type)))
+		}
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// columnProblems describes missing and unexpected columns of the expected
+// tables
+func columnProblems(columns map[string]map[string]bool, expected map[string][]string) []string {
+	var problems []string
+	for _, table := range tableNamesOf(expected) {
+		want := map[string]bool{}
+		for _, column := range expected[table] {
+			want[column] = true
+			if !columns[table][column] {
+				problems = append(problems, fmt.Sprintf("%s is missing column %s", table, column))
+			}
+		}
+
+		var extra []string
+		for column := range columns[table] {
+			if !want[column] {
+				extra = append(extra, column)
+			}
+		}
+		sort.Strings(extra)
+		for _, column := range extra {
+			problems = append(problems, fmt.Sprintf("%s has unexpected column %s", table, column))
+		}
+	}
+	return problems
+}
+
+func hasTables(columns map[string]map[string]bool, names []string) bool {
+	for _, name := range names {
+		if columns[name] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func tableNamesOf(tables map[string][]string) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
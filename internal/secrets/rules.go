@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+)
+
+// Rule detects one kind of secret in text
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	// Pattern is a regular expression matching the secret or its surroundings
+	Pattern string `json:"pattern"`
+	// Group selects the capture group holding the secret; zero means the whole match
+	Group int `json:"group,omitempty"`
+	// MinEntropy rejects matches whose secret has a lower Shannon entropy in bits per character
+	MinEntropy float64 `json:"minEntropy,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// rulesFile is the on-disk format of user-defined rules
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// DefaultRules returns the built-in detection rules
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:          "aws-access-key-id",
+			Description: "AWS access key ID",
+			Pattern:     `\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16})\b`,
+			Group:       1,
+		},
+		{
+			ID:          "aws-secret-access-key",
+			Description: "AWS secret access key",
+			Pattern:     `(?i)aws.{0,20}?(?:secret|key).{0,20}?[:=\s"']+([A-Za-z0-9/+]{40})\b`,
+			Group:       1,
+			MinEntropy:  4.0,
+		},
+		{
+			ID:          "github-token",
+			Description: "GitHub token",
+			Pattern:     `\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{60,255})\b`,
+			Group:       1,
+		},
+		{
+			ID:          "jwt",
+			Description: "JSON Web Token",
+			Pattern:     `\b(eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,})`,
+			Group:       1,
+		},
+		{
+			ID:          "private-key",
+			Description: "Private key block",
+			Pattern:     `-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?(?:-----END (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----|\z)`,
+		},
+		{
+			ID:          "slack-token",
+			Description: "Slack token",
+			Pattern:     `\b(xox[abposr]-[A-Za-z0-9-]{10,})\b`,
+			Group:       1,
+		},
+		{
+			ID:          "slack-webhook",
+			Description: "Slack incoming webhook URL",
+			Pattern:     `(https://hooks\.slack\.com/services/T[A-Za-z0-9]+/B[A-Za-z0-9]+/[A-Za-z0-9]+)`,
+			Group:       1,
+		},
+		{
+			ID:          "password-assignment",
+			Description: "Password following a password label",
+			Pattern:     `(?i)\b(?:password|passwd|passphrase|pwd|pass)\b["']?\s*[:=]\s*["']?([^\s"',;]{6,})`,
+			Group:       1,
+			MinEntropy:  2.5,
+		},
+	}
+}
+
+// DefaultRulesPath returns the default location of the user rules file
+func DefaultRulesPath() string {
+	usr, err := user.Current()
+	if err != nil {
+		return "rules.json"
+	}
+	return filepath.Join(usr.HomeDir, ".config", "sunlitsparrow", "rules.json")
+}
+
+// LoadRules returns the built-in rules extended with the rules in path. A
+// user rule with the ID of a built-in rule replaces it. A missing file is
+// not an error when optional is set.
+func LoadRules(path string, optional bool) ([]Rule, error) {
+	rules := DefaultRules()
+	if path == "" {
+		return compileRules(rules)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && optional {
+		return compileRules(rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file: %w", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing rules file: %w", err)
+	}
+
+	for _, userRule := range file.Rules {
+		replaced := false
+		for i := range rules {
+			if rules[i].ID == userRule.ID {
+				rules[i] = userRule
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rules = append(rules, userRule)
+		}
+	}
+
+	return compileRules(rules)
+}
+
+func compileRules(rules []Rule) ([]Rule, error) {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule %d has no id", i+1)
+		}
+
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q has invalid pattern: %w", rule.ID, err)
+		}
+		if rule.Group > pattern.NumSubexp() {
+			return nil, fmt.Errorf("rule %q references group %d but pattern has %d", rule.ID, rule.Group, pattern.NumSubexp())
+		}
+		rule.pattern = pattern
+	}
+	return rules, nil
+}
@@ -0,0 +1,211 @@
+package secrets
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+const (
+	// excerptContext is the number of characters shown around a masked secret
+	excerptContext = 20
+	// maxMaskStars caps the masked part so long tokens keep excerpts readable
+	maxMaskStars = 16
+)
+
+// Match locates a secret within a text
+type Match struct {
+	Rule  *Rule
+	Start int
+	End   int
+}
+
+// Finding is a secret detected in a history item
+type Finding struct {
+	ItemID      int    `json:"itemId"`
+	Application string `json:"application,omitempty"`
+	ContentType string `json:"contentType"`
+	Rule        string `json:"rule"`
+	Description string `json:"description,omitempty"`
+	Excerpt     string `json:"excerpt"`
+}
+
+// Scanner applies detection rules to text
+type Scanner struct {
+	rules []Rule
+}
+
+// NewScanner creates a scanner for compiled rules as returned by LoadRules
+func NewScanner(rules []Rule) *Scanner {
+	return &Scanner{rules: rules}
+}
+
+// ScanText returns the secrets found in text, ordered by position, with
+// overlapping matches of later rules dropped
+func (s *Scanner) ScanText(text string) []Match {
+	var matches []Match
+
+	for i := range s.rules {
+		rule := &s.rules[i]
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*rule.Group], loc[2*rule.Group+1]
+			if start < 0 {
+				continue
+			}
+			if rule.MinEntropy > 0 && shannonEntropy(text[start:end]) < rule.MinEntropy {
+				continue
+			}
+			matches = append(matches, Match{Rule: rule, Start: start, End: end})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+
+	// Drop matches overlapping an earlier one
+	kept := matches[:0]
+	lastEnd := -1
+	for _, m := range matches {
+		if m.Start < lastEnd {
+			continue
+		}
+		kept = append(kept, m)
+		lastEnd = m.End
+	}
+	return kept
+}
+
+// ScanItem scans every textual content of the item. A secret found in
+// several representations of the item, such as its plain text and HTML, is
+// reported once, for the first content holding it.
+func (s *Scanner) ScanItem(item history.HistoryItem) []Finding {
+	var findings []Finding
+	seen := map[string]bool{}
+
+	for _, content := range item.Contents {
+		if !content.IsText() {
+			continue
+		}
+		text := content.DecodedText()
+		matches := s.ScanText(text)
+		for i, m := range matches {
+			key := m.Rule.ID + "\x00" + text[m.Start:m.End]
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			findings = append(findings, Finding{
+				ItemID:      item.ID,
+				Application: item.Application,
+				ContentType: content.Type,
+				Rule:        m.Rule.ID,
+				Description: m.Rule.Description,
+				Excerpt:     excerpt(text, matches, i),
+			})
+		}
+	}
+
+	return findings
+}
+
+// Mask hides all but a few leading and trailing characters of a secret
+func Mask(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	visible := 4
+	if len(runes) < 16 {
+		visible = 2
+	}
+	hidden := min(len(runes)-2*visible, maxMaskStars)
+	return string(runes[:visible]) + strings.Repeat("*", hidden) + string(runes[len(runes)-visible:])
+}
+
+// excerpt returns the text surrounding matches[i] with whitespace collapsed
+// onto one line. Every match within the excerpt is masked, not only the one
+// reported, so neighbouring secrets are not shown either; a match cut by the
+// edge of the excerpt is included whole, masked.
+func excerpt(text string, matches []Match, i int) string {
+	m := matches[i]
+	start := runeOffset(text, m.Start, -excerptContext)
+	end := runeOffset(text, m.End, excerptContext)
+	for _, other := range matches {
+		if other.Start < start && other.End > start {
+			start = other.Start
+		}
+		if other.Start < end && other.End > end {
+			end = other.End
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, other := range matches {
+		if other.End <= start || other.Start >= end {
+			continue
+		}
+		b.WriteString(text[pos:other.Start])
+		b.WriteString(maskSecret(text[other.Start:other.End]))
+		pos = other.End
+	}
+	b.WriteString(text[pos:end])
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// runeOffset moves the byte offset pos of text by n runes, forwards for a
+// positive n and backwards for a negative one, stopping at either end
+func runeOffset(text string, pos, n int) int {
+	for ; n < 0 && pos > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:pos])
+		pos -= size
+	}
+	for ; n > 0 && pos < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// maskSecret masks a matched secret for an excerpt. Key blocks keep their
+// header line so the kind of key stays visible.
+func maskSecret(secret string) string {
+	if header, _, multiline := strings.Cut(secret, "\n"); multiline {
+		return header + " ********"
+	}
+	return Mask(secret)
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := map[rune]int{}
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
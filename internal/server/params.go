@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+)
+
+// parseFilter builds a history filter from the request query parameters:
+// app (repeatable), type, q, pinned, since, until, sort, cursor, limit and
+// offset
+func parseFilter(r *http.Request) (history.Filter, error) {
+	query := r.URL.Query()
+	filter := history.Filter{
+		Applications: query["app"],
+		ContentType:  query.Get("type"),
+		Text:         query.Get("q"),
+		Limit:        defaultPageSize,
+	}
+
+	if value := query.Get("pinned"); value != "" {
+		pinned, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid pinned value: %q", value)
+		}
+		filter.PinnedOnly = pinned
+	}
+
+	var err error
+	if filter.Since, err = history.ParseTime(query.Get("since")); err != nil {
+		return filter, fmt.Errorf("invalid since value: %w", err)
+	}
+	if filter.Until, err = history.ParseTime(query.Get("until")); err != nil {
+		return filter, fmt.Errorf("invalid until value: %w", err)
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return filter, fmt.Errorf("invalid limit value: %q", value)
+		}
+		filter.Limit = min(limit, maxPageSize)
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("invalid offset value: %q", value)
+		}
+		filter.Offset = offset
+	}
+
+	if filter.Sort, err = history.ParseSort(query.Get("sort")); err != nil {
+		return filter, fmt.Errorf("invalid sort value: %w", err)
+	}
+	if value := query.Get("cursor"); value != "" {
+		if filter.Offset > 0 {
+			return filter, fmt.Errorf("cursor and offset cannot be combined")
+		}
+		if filter.After, err = history.ParseCursor(value, filter.Sort); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
+
+// mimeType maps a pasteboard type to an HTTP content type
+func mimeType(pasteboardType string) string {
+	switch pasteboardType {
+	case history.ContentTypeText:
+		return "text/plain; charset=utf-8"
+	case history.ContentTypeHTML:
+		return "text/html; charset=utf-8"
+	case history.ContentTypeRTF:
+		return "application/rtf"
+	case history.ContentTypePNG:
+		return "image/png"
+	case history.ContentTypeTIFF:
+		return "image/tiff"
+	case history.ContentTypeFileURL:
+		return "text/uri-list"
+	default:
+		return "application/octet-stream"
+	}
+}
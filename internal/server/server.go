@@ -0,0 +1,281 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+	"github.com/gkwa/sunlitsparrow/internal/redact"
+	"github.com/gkwa/sunlitsparrow/internal/schema"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// Server exposes the clipboard history over a read-only HTTP JSON API
+type Server struct {
+	repo     *history.Repository
+	explorer *schema.Explorer
+	token    string
+	redactor *redact.Redactor
+	hosts    map[string]bool
+	mux      *http.ServeMux
+}
+
+// itemsPage is the response body of the items listing
+type itemsPage struct {
+	Items      []history.HistoryItem `json:"items"`
+	Count      int                   `json:"count"`
+	Limit      int                   `json:"limit"`
+	Offset     int                   `json:"offset"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+// errorResponse is the response body for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a new API server. When token is non-empty every request
+// must carry it as a bearer token.
+func NewServer(repo *history.Repository, explorer *schema.Explorer, token string) *Server {
+	s := &Server{
+		repo:     repo,
+		explorer: explorer,
+		token:    token,
+		hosts:    map[string]bool{"localhost": true},
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /items", s.handleItems)
+	s.mux.HandleFunc("GET /items/{id}", s.handleItem)
+	s.mux.HandleFunc("GET /items/{id}/contents/{type}", s.handleContent)
+	s.mux.HandleFunc("GET /pins", s.handlePins)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	s.mux.HandleFunc("GET /schema", s.handleSchema)
+	s.mux.Handle("GET /{$}", uiHandler())
+	s.mux.Handle("GET /ui/", uiHandler())
+
+	return s
+}
+
+// SetRedactor masks sensitive data in every item served. A nil redactor
+// disables redaction.
+func (s *Server) SetRedactor(redactor *redact.Redactor) {
+	s.redactor = redactor
+}
+
+// AllowHost accepts requests whose Host header names addr, in addition to
+// localhost and IP literals. A port in addr is ignored.
+func (s *Server) AllowHost(addr string) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if addr != "" {
+		s.hosts[strings.ToLower(addr)] = true
+	}
+}
+
+// allowedHost guards against DNS rebinding: a page served from another
+// origin that resolves its own name to the loopback address sends that
+// name, not ours, in the Host header
+func (s *Server) allowedHost(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	return s.hosts[strings.ToLower(host)]
+}
+
+// redactItems applies the configured redactor, if any
+func (s *Server) redactItems(items []history.HistoryItem) []history.HistoryItem {
+	if s.redactor == nil {
+		return items
+	}
+	redacted, _ := s.redactor.RedactItems(items)
+	return redacted
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if !s.allowedHost(r) {
+		writeError(w, http.StatusMisdirectedRequest, fmt.Errorf("unexpected host %q", r.Host))
+		return
+	}
+
+	if !isUIRequest(r) && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sunlitsparrow"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+	logger.Debug("Handled request", "method", r.Method, "path", r.URL.RequestURI(), "duration", time.Since(start))
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, err := s.repo.GetItems(filter)
+	if errors.Is(err, history.ErrCursorItemGone) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if items == nil {
+		items = []history.HistoryItem{}
+	}
+
+	page := itemsPage{
+		Items:  s.redactItems(items),
+		Count:  len(items),
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	if cursor := filter.NextCursor(items); cursor != nil {
+		page.NextCursor = cursor.String()
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.lookupItem(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleContent(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.lookupItem(w, r)
+	if !ok {
+		return
+	}
+
+	contentType := r.PathValue("type")
+	for _, content := range item.Contents {
+		if content.Type != contentType {
+			continue
+		}
+		// Contents are whatever was copied, so they must never run as a page
+		// on this origin: scripts are sandboxed away, the type is not
+		// sniffed, and HTML is downloaded rather than rendered when opened
+		// directly. The UI renders HTML in a sandboxed frame instead.
+		w.Header().Set("Content-Type", mimeType(content.Type))
+		w.Header().Set("Content-Length", strconv.Itoa(len(content.Value)))
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if content.Type == history.ContentTypeHTML {
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="item-%d.html"`, item.ID))
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(content.Value)
+		return
+	}
+
+	writeError(w, http.StatusNotFound, errors.New("content type not found for item"))
+}
+
+func (s *Server) handlePins(w http.ResponseWriter, r *http.Request) {
+	items, err := s.repo.GetPinnedItems()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if items == nil {
+		items = []history.HistoryItem{}
+	}
+	writeJSON(w, http.StatusOK, s.redactItems(items))
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.repo.GetStats()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	tables, err := s.explorer.Tables()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tables)
+}
+
+// lookupItem resolves the {id} path value, writing an error response on failure
+func (s *Server) lookupItem(w http.ResponseWriter, r *http.Request) (history.HistoryItem, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid item id"))
+		return history.HistoryItem{}, false
+	}
+
+	item, err := s.repo.GetItem(id)
+	if errors.Is(err, history.ErrItemNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return history.HistoryItem{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return history.HistoryItem{}, false
+	}
+
+	if s.redactor != nil {
+		item, _ = s.redactor.RedactItem(item)
+	}
+	return item, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		logger.Debug("Error encoding response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
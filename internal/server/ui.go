@@ -0,0 +1,26 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// uiHandler serves the embedded single-page web interface
+func uiHandler() http.Handler {
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(root)
+}
+
+// isUIRequest reports whether the request targets a static UI asset. These
+// are served without authentication; the page itself asks for the token.
+func isUIRequest(r *http.Request) bool {
+	return r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, "/ui/")
+}
@@ -0,0 +1,185 @@
+"use strict";
+
+const pageSize = 50;
+const textTypes = new Set(["public.utf8-plain-text", "public.file-url", "public.rtf"]);
+const imageTypes = new Set(["public.png", "public.tiff"]);
+const extensions = {
+  "public.utf8-plain-text": "txt",
+  "public.html": "html",
+  "public.rtf": "rtf",
+  "public.png": "png",
+  "public.tiff": "tiff",
+  "public.file-url": "txt",
+};
+
+const state = { offset: 0, selected: null };
+
+const $ = (id) => document.getElementById(id);
+
+async function api(path) {
+  const headers = {};
+  const token = sessionStorage.getItem("token");
+  if (token) {
+    headers.Authorization = `Bearer ${token}`;
+  }
+
+  const response = await fetch(path, { headers });
+  if (response.status === 401) {
+    await login();
+    return api(path);
+  }
+  if (!response.ok) {
+    throw new Error(`${path}: ${response.status} ${response.statusText}`);
+  }
+  return response;
+}
+
+function login() {
+  return new Promise((resolve) => {
+    const dialog = $("login");
+    dialog.addEventListener("close", () => {
+      sessionStorage.setItem("token", $("token").value);
+      resolve();
+    }, { once: true });
+    dialog.showModal();
+  });
+}
+
+function query(offset) {
+  const params = new URLSearchParams({ limit: pageSize, offset });
+  if ($("q").value) params.set("q", $("q").value);
+  if ($("app").value) params.set("app", $("app").value);
+  if ($("type").value) params.set("type", $("type").value);
+  return params;
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "never";
+}
+
+function renderItem(item) {
+  const li = document.createElement("li");
+  li.dataset.id = item.id;
+
+  const title = document.createElement("div");
+  title.className = "title";
+  title.textContent = item.title || "(untitled)";
+
+  const meta = document.createElement("div");
+  meta.className = "meta";
+  const pin = item.pin ? `pin ${item.pin} · ` : "";
+  meta.textContent = `${pin}${item.application || "unknown"} · ${formatTime(item.lastCopiedAt)} · ${item.numberOfCopies}×`;
+
+  li.append(title, meta);
+  li.addEventListener("click", () => select(item.id));
+  return li;
+}
+
+async function loadItems(append) {
+  state.offset = append ? state.offset + pageSize : 0;
+  const page = await (await api(`/items?${query(state.offset)}`)).json();
+
+  const list = $("items");
+  if (!append) list.replaceChildren();
+  page.items.forEach((item) => list.append(renderItem(item)));
+  $("more").hidden = page.count < pageSize;
+}
+
+async function loadPins() {
+  const pins = await (await api("/pins")).json();
+  $("pins").replaceChildren(...pins.map(renderItem));
+}
+
+async function loadApplications() {
+  const stats = await (await api("/stats")).json();
+  const select = $("app");
+  for (const app of stats.applications || []) {
+    if (!app.application) continue;
+    const option = document.createElement("option");
+    option.value = app.application;
+    option.textContent = `${app.application} (${app.items})`;
+    select.append(option);
+  }
+}
+
+async function contentBlob(id, type) {
+  const response = await api(`/items/${id}/contents/${encodeURIComponent(type)}`);
+  return response.blob();
+}
+
+async function renderContent(item, content) {
+  const section = document.createElement("section");
+  section.className = "content";
+
+  const header = document.createElement("header");
+  const label = document.createElement("span");
+  label.textContent = content.type;
+  const download = document.createElement("button");
+  download.textContent = "Download";
+  download.addEventListener("click", async () => {
+    const link = document.createElement("a");
+    link.href = URL.createObjectURL(await contentBlob(item.id, content.type));
+    link.download = `item-${item.id}.${extensions[content.type] || "bin"}`;
+    link.click();
+    URL.revokeObjectURL(link.href);
+  });
+  header.append(label, download);
+  section.append(header);
+
+  if (imageTypes.has(content.type)) {
+    const img = document.createElement("img");
+    img.src = URL.createObjectURL(await contentBlob(item.id, content.type));
+    img.alt = item.title;
+    section.append(img);
+  } else if (content.type === "public.html") {
+    const frame = document.createElement("iframe");
+    frame.sandbox = "";
+    frame.srcdoc = await (await contentBlob(item.id, content.type)).text();
+    section.append(frame);
+  } else if (textTypes.has(content.type)) {
+    const pre = document.createElement("pre");
+    pre.textContent = await (await contentBlob(item.id, content.type)).text();
+    section.append(pre);
+  }
+
+  return section;
+}
+
+async function select(id) {
+  state.selected = id;
+  document.querySelectorAll(".items li").forEach((li) => {
+    li.classList.toggle("selected", Number(li.dataset.id) === id);
+  });
+
+  const item = await (await api(`/items/${id}`)).json();
+  if (state.selected !== id) return;
+
+  const detail = $("detail");
+  const heading = document.createElement("h2");
+  heading.textContent = item.title || "(untitled)";
+  const meta = document.createElement("p");
+  meta.className = "meta";
+  meta.textContent = `#${item.id} · ${item.application || "unknown"} · first copied ${formatTime(item.firstCopiedAt)} · last copied ${formatTime(item.lastCopiedAt)} · ${item.numberOfCopies} copies`;
+
+  const sections = await Promise.all((item.contents || []).map((content) => renderContent(item, content)));
+  detail.replaceChildren(heading, meta, ...sections);
+}
+
+function debounce(fn, delay) {
+  let timer;
+  return (...args) => {
+    clearTimeout(timer);
+    timer = setTimeout(() => fn(...args), delay);
+  };
+}
+
+$("q").addEventListener("input", debounce(() => loadItems(false), 250));
+$("app").addEventListener("change", () => loadItems(false));
+$("type").addEventListener("change", () => loadItems(false));
+$("filters").addEventListener("submit", (event) => event.preventDefault());
+$("more").addEventListener("click", () => loadItems(true));
+
+(async () => {
+  await loadItems(false);
+  await Promise.all([loadPins(), loadApplications()]);
+})();
@@ -0,0 +1,177 @@
+package snippets
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"gopkg.in/yaml.v3"
+)
+
+// Snippet is a pinned item declared in a snippets file
+type Snippet struct {
+	Pin   string `yaml:"pin" json:"pin"`
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	Text  string `yaml:"text" json:"text"`
+}
+
+// file is the layout of a snippets file
+type file struct {
+	Snippets []Snippet `yaml:"snippets" json:"snippets"`
+}
+
+// Load reads a YAML or JSON snippets file and validates its pin keys
+func Load(path string) ([]Snippet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snippets file: %w", err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing snippets file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range f.Snippets {
+		snippet := &f.Snippets[i]
+		snippet.Pin = strings.ToLower(strings.TrimSpace(snippet.Pin))
+
+		if !slices.Contains(history.PinKeys, snippet.Pin) {
+			return nil, fmt.Errorf("snippet %d has invalid pin key %q (expected one of %s)",
+				i+1, snippet.Pin, strings.Join(history.PinKeys, ""))
+		}
+		if seen[snippet.Pin] {
+			return nil, fmt.Errorf("pin key %q is declared more than once", snippet.Pin)
+		}
+		seen[snippet.Pin] = true
+
+		if snippet.Text == "" {
+			return nil, fmt.Errorf("snippet %q has no text", snippet.Pin)
+		}
+		if snippet.Title == "" {
+			// Maccy titles items with their trimmed text
+			snippet.Title = strings.TrimSpace(snippet.Text)
+		}
+	}
+
+	return f.Snippets, nil
+}
+
+// Change is a planned change together with the state it replaces
+type Change struct {
+	history.PinChange
+	OldTitle string
+	OldText  string
+}
+
+// Plan compares the pinned items with the declared snippets and returns the
+// changes that reconcile them, ordered by pin key. Pinned items without a
+// snippet are unpinned only when prune is set.
+func Plan(pinned []history.HistoryItem, snippets []Snippet, prune bool) []Change {
+	byPin := make(map[string]history.HistoryItem, len(pinned))
+	for _, item := range pinned {
+		byPin[item.Pin] = item
+	}
+
+	var changes []Change
+	declared := map[string]bool{}
+
+	for _, snippet := range snippets {
+		declared[snippet.Pin] = true
+
+		item, exists := byPin[snippet.Pin]
+		if !exists {
+			changes = append(changes, Change{PinChange: history.PinChange{
+				Action: history.PinCreate,
+				Pin:    snippet.Pin,
+				Title:  snippet.Title,
+				Text:   snippet.Text,
+			}})
+			continue
+		}
+
+		if item.Title == snippet.Title && item.Text() == snippet.Text {
+			continue
+		}
+		changes = append(changes, Change{
+			PinChange: history.PinChange{
+				Action: history.PinUpdate,
+				ItemID: item.ID,
+				Pin:    snippet.Pin,
+				Title:  snippet.Title,
+				Text:   snippet.Text,
+			},
+			OldTitle: item.Title,
+			OldText:  item.Text(),
+		})
+	}
+
+	if prune {
+		for _, item := range pinned {
+			if declared[item.Pin] {
+				continue
+			}
+			changes = append(changes, Change{
+				PinChange: history.PinChange{
+					Action: history.PinUnpin,
+					ItemID: item.ID,
+					Pin:    item.Pin,
+				},
+				OldTitle: item.Title,
+				OldText:  item.Text(),
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Pin < changes[j].Pin
+	})
+	return changes
+}
+
+// FormatDiff renders the planned changes as a diff-like preview
+func FormatDiff(changes []Change) string {
+	var b strings.Builder
+
+	for _, change := range changes {
+		switch change.Action {
+		case history.PinCreate:
+			fmt.Fprintf(&b, "+ [%s] create %q\n", change.Pin, change.Title)
+			writeLines(&b, "+     ", change.Text)
+		case history.PinUpdate:
+			fmt.Fprintf(&b, "~ [%s] update item %d\n", change.Pin, change.ItemID)
+			if change.OldTitle != change.Title {
+				fmt.Fprintf(&b, "-     title: %q\n", change.OldTitle)
+				fmt.Fprintf(&b, "+     title: %q\n", change.Title)
+			}
+			if change.OldText != change.Text {
+				writeLines(&b, "-     ", change.OldText)
+				writeLines(&b, "+     ", change.Text)
+			}
+		case history.PinUnpin:
+			fmt.Fprintf(&b, "- [%s] unpin item %d %q\n", change.Pin, change.ItemID, change.OldTitle)
+		}
+	}
+
+	return b.String()
+}
+
+func writeLines(b *strings.Builder, prefix, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+}
+
+// PinChanges strips the preview information from planned changes
+func PinChanges(changes []Change) []history.PinChange {
+	result := make([]history.PinChange, len(changes))
+	for i, change := range changes {
+		result[i] = change.PinChange
+	}
+	return result
+}
@@ -0,0 +1,235 @@
+package table
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// Align is the horizontal alignment of a column
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Column describes one column of a table
+type Column struct {
+	Header string
+	Align  Align
+	// Max caps the width of the column in terminal cells; zero means no cap
+	Max int
+	// Flex marks a column that may be narrowed, truncating its cells, for
+	// the table to fit the terminal
+	Flex bool
+}
+
+// gap separates adjacent columns
+const gap = "  "
+
+// ellipsis marks truncated cells
+const ellipsis = "…"
+
+// ANSI styles used when color is enabled
+const (
+	styleHeader = "\x1b[1m"
+	styleRule   = "\x1b[2m"
+	styleReset  = "\x1b[0m"
+)
+
+// Table renders rows of cells as aligned columns. Widths are measured in
+// terminal cells, so East Asian wide characters and emoji line up, and
+// control characters such as newlines are shown escaped.
+type Table struct {
+	columns []Column
+	rows    [][]string
+
+	// width is the maximum line width, or zero for no limit
+	width int
+}
+
+// New creates a table with the given columns
+func New(columns ...Column) *Table {
+	return &Table{columns: columns}
+}
+
+// Append adds a row. Missing cells are left empty and extra cells are
+// ignored.
+func (t *Table) Append(cells ...string) {
+	row := make([]string, len(t.columns))
+	for i := range row {
+		if i < len(cells) {
+			row[i] = Escape(cells[i])
+		}
+	}
+	t.rows = append(t.rows, row)
+}
+
+// Len returns the number of rows
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// SetWidth limits lines to the given number of terminal cells, overriding
+// the width detected by Render; zero means no limit
+func (t *Table) SetWidth(width int) {
+	t.width = width
+}
+
+// Render writes the table. When w is a terminal, the table is narrowed to
+// the terminal width and the header is styled unless NO_COLOR is set.
+func (t *Table) Render(w io.Writer) error {
+	width, color := t.width, false
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if width == 0 {
+			width = terminalWidth(f)
+		}
+		color = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	}
+
+	widths := t.layout(width)
+
+	var b strings.Builder
+	headers := make([]string, len(t.columns))
+	rules := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		headers[i] = column.Header
+		rules[i] = strings.Repeat("-", widths[i])
+	}
+	t.writeRow(&b, headers, widths, styleHeader, color)
+	t.writeRow(&b, rules, widths, styleRule, color)
+	for _, row := range t.rows {
+		t.writeRow(&b, row, widths, "", false)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (t *Table) writeRow(b *strings.Builder, cells []string, widths []int, style string, color bool) {
+	var line strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString(gap)
+		}
+		cell = runewidth.Truncate(cell, widths[i], ellipsis)
+		switch {
+		case t.columns[i].Align == AlignRight:
+			cell = runewidth.FillLeft(cell, widths[i])
+		case i < len(cells)-1:
+			cell = runewidth.FillRight(cell, widths[i])
+		}
+		line.WriteString(cell)
+	}
+
+	text := strings.TrimRight(line.String(), " ")
+	if color && style != "" {
+		text = style + text + styleReset
+	}
+	b.WriteString(text)
+	b.WriteString("\n")
+}
+
+// layout computes the column widths: each column is as wide as its widest
+// cell up to its Max, and flexible columns give up space, widest first,
+// until the table fits the given width
+func (t *Table) layout(width int) []int {
+	widths := make([]int, len(t.columns))
+	minimums := make([]int, len(t.columns))
+	for i, column := range t.columns {
+		header := runewidth.StringWidth(column.Header)
+		widths[i] = header
+		for _, row := range t.rows {
+			widths[i] = max(widths[i], runewidth.StringWidth(row[i]))
+		}
+		if column.Max > 0 {
+			widths[i] = min(widths[i], max(column.Max, header))
+		}
+		minimums[i] = widths[i]
+		if column.Flex {
+			minimums[i] = min(widths[i], max(header, 8))
+		}
+	}
+
+	if width <= 0 {
+		return widths
+	}
+
+	total := len(gap) * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := -1
+		for i := range widths {
+			if widths[i] > minimums[i] && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// terminalWidth returns the width of the terminal, falling back to $COLUMNS
+func terminalWidth(f *os.File) int {
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// Escape replaces control characters with backslash escapes so a cell
+// stays on one line and cannot emit terminal control sequences
+func Escape(s string) string {
+	return escape(s, false)
+}
+
+// EscapeText is like Escape but keeps newlines and tabs, for multi-line
+// text such as item previews
+func EscapeText(s string) string {
+	return escape(s, true)
+}
+
+func escape(s string, multiline bool) string {
+	if !strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsControl(r) && !(multiline && (r == '\n' || r == '\t'))
+	}) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case multiline && (r == '\n' || r == '\t'):
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case unicode.IsControl(r) && r < 0x100:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,340 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/table"
+)
+
+// sortOrder selects how the item list is ordered
+type sortOrder int
+
+const (
+	sortByRecency sortOrder = iota
+	sortByCopies
+)
+
+func (s sortOrder) String() string {
+	if s == sortByCopies {
+		return "copies"
+	}
+	return "recency"
+}
+
+var (
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("214"))
+	pinStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	borderStyle   = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("240")).PaddingLeft(1)
+)
+
+// Browser is the bubbletea model of the interactive history browser
+type Browser struct {
+	items    []history.HistoryItem
+	visible  []history.HistoryItem
+	cursor   int
+	offset   int
+	filter   textinput.Model
+	preview  viewport.Model
+	details  bool
+	pinned   bool
+	order    sortOrder
+	width    int
+	height   int
+	selected *history.HistoryItem
+}
+
+// NewBrowser creates a browser over the given items
+func NewBrowser(items []history.HistoryItem) *Browser {
+	filter := textinput.New()
+	filter.Prompt = "/ "
+	filter.Placeholder = "type to filter"
+
+	b := &Browser{
+		items:  items,
+		filter: filter,
+	}
+	b.refresh()
+	return b
+}
+
+// Run shows the browser on the terminal and returns the item chosen by the
+// user, or nil when the browser was dismissed
+func Run(items []history.HistoryItem) (*history.HistoryItem, error) {
+	// Render on stderr so stdout stays free for the selected text
+	program := tea.NewProgram(NewBrowser(items),
+		tea.WithAltScreen(),
+		tea.WithInputTTY(),
+		tea.WithOutput(os.Stderr),
+	)
+
+	model, err := program.Run()
+	if err != nil {
+		return nil, err
+	}
+	return model.(*Browser).selected, nil
+}
+
+// Init implements tea.Model
+func (b *Browser) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model
+func (b *Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+		b.resize()
+		return b, nil
+	case tea.KeyMsg:
+		return b.handleKey(msg)
+	}
+	return b, nil
+}
+
+func (b *Browser) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return b, tea.Quit
+	case "enter":
+		if item := b.current(); item != nil {
+			b.selected = item
+		}
+		return b, tea.Quit
+	case "up", "ctrl+p":
+		b.move(-1)
+		return b, nil
+	case "down", "ctrl+n":
+		b.move(1)
+		return b, nil
+	case "pgup":
+		b.move(-b.listHeight())
+		return b, nil
+	case "pgdown":
+		b.move(b.listHeight())
+		return b, nil
+	}
+
+	if b.details {
+		switch msg.String() {
+		case "esc", "q", "d":
+			b.details = false
+			b.updatePreview()
+			return b, nil
+		}
+		var cmd tea.Cmd
+		b.preview, cmd = b.preview.Update(msg)
+		return b, cmd
+	}
+
+	if b.filter.Focused() {
+		switch msg.String() {
+		case "esc":
+			b.filter.Blur()
+			return b, nil
+		}
+		var cmd tea.Cmd
+		b.filter, cmd = b.filter.Update(msg)
+		b.refresh()
+		return b, cmd
+	}
+
+	switch msg.String() {
+	case "q", "esc":
+		return b, tea.Quit
+	case "/":
+		return b, b.filter.Focus()
+	case "k":
+		b.move(-1)
+	case "j":
+		b.move(1)
+	case "g", "home":
+		b.move(-len(b.visible))
+	case "G", "end":
+		b.move(len(b.visible))
+	case "p":
+		b.pinned = !b.pinned
+		b.refresh()
+	case "s":
+		b.order = (b.order + 1) % 2
+		b.refresh()
+	case "d":
+		if b.current() != nil {
+			b.details = true
+			b.updatePreview()
+		}
+	}
+	return b, nil
+}
+
+// View implements tea.Model
+func (b *Browser) View() string {
+	if b.width == 0 {
+		return ""
+	}
+
+	header := b.filter.View()
+	status := dimStyle.Render(fmt.Sprintf("%d/%d items · sort: %s · pinned only: %t · enter select · d details · p pins · s sort · / filter · q quit",
+		len(b.visible), len(b.items), b.order, b.pinned))
+	status = ansi.Truncate(status, b.width, "…")
+
+	if b.details {
+		return lipgloss.JoinVertical(lipgloss.Left, header, b.preview.View(), status)
+	}
+
+	list := lipgloss.NewStyle().Width(b.listWidth()).Height(b.listHeight()).Render(b.renderList())
+	preview := borderStyle.Render(b.preview.View())
+	body := lipgloss.JoinHorizontal(lipgloss.Top, list, preview)
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, body, status)
+}
+
+func (b *Browser) renderList() string {
+	var lines []string
+	height := b.listHeight()
+	width := b.listWidth()
+
+	for i := b.offset; i < len(b.visible) && i < b.offset+height; i++ {
+		item := b.visible[i]
+
+		pin := "  "
+		if item.Pin != "" {
+			pin = pinStyle.Render(fmt.Sprintf("%-2s", item.Pin))
+		}
+		title := table.Escape(strings.Join(strings.Fields(item.Title), " "))
+		line := fmt.Sprintf("%s %4d  %s", pin, item.NumberOfCopies, title)
+		line = ansi.Truncate(line, width, "…")
+
+		if i == b.cursor {
+			line = selectedStyle.Render(ansi.Strip(line) + strings.Repeat(" ", max(0, width-ansi.StringWidth(line))))
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return dimStyle.Render("No matching items.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// refresh recomputes the visible items after a filter, toggle or sort change
+func (b *Browser) refresh() {
+	terms := strings.Fields(strings.ToLower(b.filter.Value()))
+
+	b.visible = b.visible[:0]
+	for _, item := range b.items {
+		if b.pinned && item.Pin == "" {
+			continue
+		}
+		if matchesTerms(item, terms) {
+			b.visible = append(b.visible, item)
+		}
+	}
+
+	sort.SliceStable(b.visible, func(i, j int) bool {
+		if b.order == sortByCopies && b.visible[i].NumberOfCopies != b.visible[j].NumberOfCopies {
+			return b.visible[i].NumberOfCopies > b.visible[j].NumberOfCopies
+		}
+		return b.visible[i].LastCopiedAt.After(b.visible[j].LastCopiedAt)
+	})
+
+	b.cursor = 0
+	b.offset = 0
+	b.updatePreview()
+}
+
+func matchesTerms(item history.HistoryItem, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	haystack := strings.ToLower(item.Title + "\n" + item.Application + "\n" + item.Text())
+	for _, term := range terms {
+		if !strings.Contains(haystack, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Browser) move(delta int) {
+	if len(b.visible) == 0 {
+		return
+	}
+	b.cursor = min(max(b.cursor+delta, 0), len(b.visible)-1)
+
+	height := b.listHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+height {
+		b.offset = b.cursor - height + 1
+	}
+	b.updatePreview()
+}
+
+func (b *Browser) current() *history.HistoryItem {
+	if b.cursor < 0 || b.cursor >= len(b.visible) {
+		return nil
+	}
+	item := b.visible[b.cursor]
+	return &item
+}
+
+func (b *Browser) resize() {
+	b.filter.Width = b.width - 3
+	if b.details {
+		b.preview.Width = b.width
+	} else {
+		b.preview.Width = b.width - b.listWidth() - 2
+	}
+	b.preview.Height = b.listHeight()
+	b.move(0)
+	b.updatePreview()
+}
+
+func (b *Browser) updatePreview() {
+	if b.details {
+		b.preview.Width = b.width
+	} else {
+		b.preview.Width = max(b.width-b.listWidth()-2, 0)
+	}
+	b.preview.Height = b.listHeight()
+
+	item := b.current()
+	if item == nil {
+		b.preview.SetContent("")
+		return
+	}
+
+	var content string
+	if b.details {
+		content = history.FormatDetails(*item)
+	} else {
+		text := item.DecodedText()
+		if text == "" && len(item.Contents) > 0 {
+			text = item.Contents[0].DecodedText()
+		}
+		header := fmt.Sprintf("#%d · %s · %s", item.ID, table.Escape(item.Application), item.LastCopiedAt.Local().Format("2006-01-02 15:04"))
+		content = dimStyle.Render(header) + "\n\n" + table.EscapeText(text)
+	}
+
+	b.preview.SetContent(lipgloss.NewStyle().Width(b.preview.Width).Render(content))
+	b.preview.GotoTop()
+}
+
+func (b *Browser) listWidth() int {
+	return max(b.width*2/5, 20)
+}
+
+func (b *Browser) listHeight() int {
+	return max(b.height-2, 1)
+}
@@ -0,0 +1,74 @@
+package watch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gkwa/sunlitsparrow/internal/history"
+	"github.com/gkwa/sunlitsparrow/internal/logger"
+)
+
+// Watcher polls the history repository for newly copied items
+type Watcher struct {
+	repo     *history.Repository
+	interval time.Duration
+}
+
+// NewWatcher creates a new watcher polling at the given interval
+func NewWatcher(repo *history.Repository, interval time.Duration) *Watcher {
+	return &Watcher{repo: repo, interval: interval}
+}
+
+// Run polls until the context is cancelled, calling handle for every item
+// copied after the watcher started, oldest first
+func (w *Watcher) Run(ctx context.Context, handle func(history.HistoryItem)) error {
+	lastSeen, err := w.latestCopyTime()
+	if err != nil {
+		return err
+	}
+	log := logger.FromContext(ctx)
+	log.Info("Watching for clipboard items", "after", lastSeen.Local().Format(time.RFC3339), "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		items, err := w.repo.GetItems(history.Filter{Since: lastSeen})
+		if err != nil {
+			log.Info("Error polling for new items", "error", err)
+			continue
+		}
+		log.Log(ctx, logger.LevelTrace, "Polled for new items", "count", len(items))
+
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].LastCopiedAt.Before(items[j].LastCopiedAt)
+		})
+
+		for _, item := range items {
+			if item.LastCopiedAt.After(lastSeen) {
+				lastSeen = item.LastCopiedAt
+			}
+			handle(item)
+		}
+	}
+}
+
+// latestCopyTime returns the last copy time of the most recent item, so that
+// only items copied after startup are reported
+func (w *Watcher) latestCopyTime() (time.Time, error) {
+	items, err := w.repo.GetItems(history.Filter{Limit: 1})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(items) == 0 {
+		return time.Now(), nil
+	}
+	return items[0].LastCopiedAt, nil
+}